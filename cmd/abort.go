@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAbortCommand() *cobra.Command {
+	opts := &AliasOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "abort",
+		Short: "Revert an in-progress traffic shift",
+		Long:  `Revert an in-progress canary or linear traffic shift by sending all traffic of the stage alias back to the previous Lambda version.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAbort(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func runAbort(opts *AliasOptions) error {
+	fmt.Printf("⏪ Aborting traffic shift for stage: %s\n", opts.Stage)
+
+	// 1. Read the stage from config.json
+	stageConfig, functionName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+
+	// 2. Abort the shift in every region of the stage, unless --region
+	// picks one. Regions without a shift in progress are left alone.
+	configs := regionalConfigs(stageConfig)
+	aborted := 0
+	for _, regional := range configs {
+		shifting, err := abortRegion(regional, functionName)
+		if err != nil {
+			return err
+		}
+		if shifting {
+			aborted++
+		} else if len(configs) > 1 {
+			fmt.Printf("No traffic shift in progress in %s\n", currentTarget)
+		}
+	}
+	if aborted == 0 {
+		return fmt.Errorf("❌ no traffic shift in progress for alias '%s'", opts.Stage)
+	}
+
+	fmt.Println("✅ All traffic is back on the previous version")
+	fmt.Printf("💡 The next 'gozap update --stage %s' will redeploy the stack as configured\n", opts.Stage)
+	return nil
+}
+
+// abortRegion sends all traffic of the stage alias in the stage's region back
+// to the previous version under the stage's deploy lock, and reports whether
+// a shift was in progress
+func abortRegion(stageConfig DeploymentConfig, functionName string) (bool, error) {
+	// Point the aws CLI at the stage's account and region
+	if err := useTarget(&stageConfig); err != nil {
+		return false, err
+	}
+
+	// Acquire the deploy lock for the stage
+	lock, err := acquireLock(stageConfig.S3Bucket, functionName, "abort")
+	if err != nil {
+		return false, err
+	}
+	defer lock.Release()
+
+	info, err := getAlias(functionName, stageConfig.Stage)
+	if err != nil {
+		return false, err
+	}
+	if pending, _ := info.pendingVersion(); pending == "" {
+		return false, nil
+	}
+	if err := abortAlias(functionName, stageConfig.Stage); err != nil {
+		return false, err
+	}
+
+	recordHistory("abort", stageConfig, functionName)
+	return true, nil
+}
@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var usagePlanName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ThrottleConfig limits the steady-state request rate and the burst size
+type ThrottleConfig struct {
+	RateLimit  float64
+	BurstLimit int
+}
+
+// UsagePlan requires API keys on the API and applies its throttle and quota
+// to every key in the plan. Keys lists keys managed by the stack; partner
+// keys are added with 'gozap apikey create'.
+type UsagePlan struct {
+	Name     string
+	Throttle *ThrottleConfig `json:",omitempty"`
+	Quota    *QuotaConfig    `json:",omitempty"`
+	Keys     []string        `json:",omitempty"`
+}
+
+type QuotaConfig struct {
+	Limit  int
+	Period string // DAY, WEEK or MONTH
+}
+
+func (t *ThrottleConfig) validate() error {
+	if t.RateLimit <= 0 || t.BurstLimit <= 0 {
+		return fmt.Errorf("❌ throttling requires a positive RateLimit and BurstLimit")
+	}
+	return nil
+}
+
+func (p UsagePlan) validate() error {
+	if !usagePlanName.MatchString(p.Name) {
+		return fmt.Errorf("❌ usage plan name '%s' may only contain letters, digits, '-' and '_'", p.Name)
+	}
+	if p.Throttle != nil {
+		if err := p.Throttle.validate(); err != nil {
+			return err
+		}
+	}
+	if q := p.Quota; q != nil {
+		if q.Limit <= 0 {
+			return fmt.Errorf("❌ quota of usage plan '%s' requires a positive Limit", p.Name)
+		}
+		if q.Period != "DAY" && q.Period != "WEEK" && q.Period != "MONTH" {
+			return fmt.Errorf("❌ quota period of usage plan '%s' must be DAY, WEEK or MONTH, got '%s'", p.Name, q.Period)
+		}
+	}
+	for _, key := range p.Keys {
+		if !usagePlanName.MatchString(key) {
+			return fmt.Errorf("❌ API key name '%s' may only contain letters, digits, '-' and '_'", key)
+		}
+	}
+	return nil
+}
+
+func validateUsagePlans(plans []UsagePlan) error {
+	names := map[string]bool{}
+	for _, plan := range plans {
+		if err := plan.validate(); err != nil {
+			return err
+		}
+		if names[plan.Name] {
+			return fmt.Errorf("❌ usage plan '%s' is defined twice", plan.Name)
+		}
+		names[plan.Name] = true
+	}
+	return nil
+}
+
+func NewApiKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage the API keys of a stage's usage plans",
+		Long:  `Create, list and revoke the API keys partners use to call a stage with UsagePlans.`,
+	}
+
+	cmd.AddCommand(newApiKeyCreateCommand())
+	cmd.AddCommand(newApiKeyListCommand())
+	cmd.AddCommand(newApiKeyRevokeCommand())
+
+	return cmd
+}
+
+func newApiKeyCreateCommand() *cobra.Command {
+	opts := &ApiKeyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API key and add it to a usage plan",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApiKeyCreate(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().StringVarP(&opts.Name, "name", "n", "", "Name of the key, e.g. the partner using it")
+	cmd.Flags().StringVarP(&opts.Plan, "plan", "p", "", "Usage plan to add the key to (required with several plans)")
+	cmd.MarkFlagRequired("stage")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func newApiKeyListCommand() *cobra.Command {
+	opts := &ApiKeyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the API keys of the stage's usage plans",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApiKeyList(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func newApiKeyRevokeCommand() *cobra.Command {
+	opts := &ApiKeyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Delete an API key created with 'apikey create'",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApiKeyRevoke(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().StringVarP(&opts.Name, "name", "n", "", "Name of the key to revoke")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation prompt")
+	cmd.MarkFlagRequired("stage")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func runApiKeyCreate(opts *ApiKeyOptions) error {
+	stageConfig, stackName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+	if !usagePlanName.MatchString(opts.Name) {
+		return fmt.Errorf("❌ API key name '%s' may only contain letters, digits, '-' and '_'", opts.Name)
+	}
+
+	plan, err := selectUsagePlan(stageConfig, opts.Plan)
+	if err != nil {
+		return err
+	}
+
+	// Every region of the stage gets the key, with the value generated in
+	// the first one, so clients can be routed to any region
+	var key *apiKey
+	for _, regional := range regionalConfigs(stageConfig) {
+		value := ""
+		if key != nil {
+			value = key.Value
+		}
+		created, err := createApiKey(regional, stackName, plan.Name, opts.Name, value)
+		if err != nil && key != nil {
+			return fmt.Errorf("%w\nThe key was already created in the previous regions, revoke it with 'gozap apikey revoke' before trying again", err)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  - ID in %s: %s\n", currentTarget, created.ID)
+		key = created
+	}
+
+	fmt.Println("✅ API key created")
+	fmt.Printf("  - Value: %s\n", key.Value)
+	fmt.Println("  Send it in the x-api-key header of every request")
+	return nil
+}
+
+type apiKey struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// createApiKey creates the key in the usage plan of the stage's region under
+// the stage's deploy lock. An empty value lets API Gateway generate one.
+func createApiKey(stageConfig DeploymentConfig, stackName, plan, name, value string) (*apiKey, error) {
+	if err := useTarget(&stageConfig); err != nil {
+		return nil, err
+	}
+	lock, err := acquireLock(stageConfig.S3Bucket, stackName, "apikey create")
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	planID, err := usagePlanID(stackName, plan)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := usagePlanKeys(planID)
+	if err != nil {
+		return nil, err
+	}
+	keyName := fmt.Sprintf("%s-%s", stackName, name)
+	for _, key := range keys {
+		if key.Name == keyName {
+			return nil, fmt.Errorf("❌ API key '%s' already exists in usage plan '%s'", name, plan)
+		}
+	}
+
+	fmt.Printf("🔑 Creating API key '%s' in usage plan '%s'...\n", name, plan)
+	args := []string{
+		"apigateway", "create-api-key",
+		"--name", keyName,
+		"--description", "Created by 'gozap apikey create'",
+		"--enabled",
+		"--output", "json",
+	}
+	if value != "" {
+		args = append(args, "--value", value)
+	}
+	output, err := awsCommand(args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w\n%s", err, output)
+	}
+
+	key := &apiKey{}
+	if err := json.Unmarshal(output, key); err != nil {
+		return nil, fmt.Errorf("failed to parse API key: %w", err)
+	}
+
+	if output, err := awsCommand(
+		"apigateway", "create-usage-plan-key",
+		"--usage-plan-id", planID,
+		"--key-id", key.ID,
+		"--key-type", "API_KEY",
+	).CombinedOutput(); err != nil {
+		// Do not leave a key behind that is not part of any plan
+		awsCommand("apigateway", "delete-api-key", "--api-key", key.ID).Run()
+		return nil, fmt.Errorf("failed to add API key to usage plan: %w\n%s", err, output)
+	}
+	return key, nil
+}
+
+func runApiKeyList(opts *ApiKeyOptions) error {
+	stageConfig, stackName, err := loadStageTarget(opts.Stage)
+	if err != nil {
+		return err
+	}
+	if len(stageConfig.UsagePlans) == 0 {
+		return fmt.Errorf("❌ stage '%s' has no UsagePlans", opts.Stage)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLAN\tNAME\tID\tMANAGED BY")
+	for _, plan := range stageConfig.UsagePlans {
+		planID, err := usagePlanID(stackName, plan.Name)
+		if err != nil {
+			return err
+		}
+		keys, err := usagePlanKeys(planID)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			name := strings.TrimPrefix(key.Name, stackName+"-")
+			managedBy := "apikey"
+			if plan.hasKey(name) {
+				managedBy = "config.json"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", plan.Name, name, key.ID, managedBy)
+		}
+	}
+	return w.Flush()
+}
+
+func runApiKeyRevoke(opts *ApiKeyOptions) error {
+	stageConfig, stackName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+	for _, plan := range stageConfig.UsagePlans {
+		if plan.hasKey(opts.Name) {
+			return fmt.Errorf("❌ API key '%s' is managed by config.json, remove it from usage plan '%s' and run 'gozap update' instead", opts.Name, plan.Name)
+		}
+	}
+
+	// The key is revoked in every region of the stage, the confirmation is
+	// asked once
+	confirmed := opts.Force
+	revoked := 0
+	for _, regional := range regionalConfigs(stageConfig) {
+		found, err := revokeApiKey(regional, stackName, opts.Name, &confirmed)
+		if err != nil {
+			return err
+		}
+		if found && !confirmed {
+			fmt.Println("❌ Revocation cancelled")
+			return nil
+		}
+		if found {
+			revoked++
+		}
+	}
+
+	if revoked == 0 {
+		return fmt.Errorf("❌ API key '%s' not found in the usage plans of stage '%s'", opts.Name, opts.Stage)
+	}
+	fmt.Printf("✅ API key '%s' revoked\n", opts.Name)
+	return nil
+}
+
+// revokeApiKey deletes the key from the stage's region under the stage's
+// deploy lock. It asks for confirmation unless confirmed is already set, and
+// reports whether the key was found.
+func revokeApiKey(stageConfig DeploymentConfig, stackName, name string, confirmed *bool) (bool, error) {
+	if err := useTarget(&stageConfig); err != nil {
+		return false, err
+	}
+	lock, err := acquireLock(stageConfig.S3Bucket, stackName, "apikey revoke")
+	if err != nil {
+		return false, err
+	}
+	defer lock.Release()
+
+	keyName := fmt.Sprintf("%s-%s", stackName, name)
+	for _, plan := range stageConfig.UsagePlans {
+		planID, err := usagePlanID(stackName, plan.Name)
+		if err != nil {
+			return false, err
+		}
+		keys, err := usagePlanKeys(planID)
+		if err != nil {
+			return false, err
+		}
+
+		for _, key := range keys {
+			if key.Name != keyName {
+				continue
+			}
+
+			if !*confirmed {
+				fmt.Printf("\n⚠️  WARNING: Requests with API key '%s' (%s) will be rejected immediately\n\n", name, key.ID)
+				if !confirmAction("Are you sure you want to revoke the key?") {
+					return true, nil
+				}
+				*confirmed = true
+			}
+
+			if output, err := awsCommand("apigateway", "delete-api-key", "--api-key", key.ID).CombinedOutput(); err != nil {
+				return true, fmt.Errorf("failed to delete API key: %w\n%s", err, output)
+			}
+			fmt.Printf("Revoked API key '%s' in %s\n", name, currentTarget)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p UsagePlan) hasKey(name string) bool {
+	for _, key := range p.Keys {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectUsagePlan returns the named plan, or the only plan of the stage
+func selectUsagePlan(stageConfig DeploymentConfig, name string) (UsagePlan, error) {
+	plans := stageConfig.UsagePlans
+	if len(plans) == 0 {
+		return UsagePlan{}, fmt.Errorf("❌ stage has no UsagePlans, add one to config.json and run 'gozap update' first")
+	}
+	if name == "" {
+		if len(plans) > 1 {
+			return UsagePlan{}, fmt.Errorf("❌ stage has several usage plans, choose one with --plan")
+		}
+		return plans[0], nil
+	}
+	for _, plan := range plans {
+		if plan.Name == name {
+			return plan, nil
+		}
+	}
+	return UsagePlan{}, fmt.Errorf("❌ usage plan '%s' not found in configuration", name)
+}
+
+// usagePlanID looks up a deployed usage plan by the name the template gives it
+func usagePlanID(stackName, plan string) (string, error) {
+	output, err := awsCommand(
+		"apigateway", "get-usage-plans",
+		"--query", fmt.Sprintf("items[?name=='%s-%s'].id | [0]", stackName, plan),
+		"--output", "text",
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list usage plans: %w", err)
+	}
+
+	id := strings.TrimSpace(string(output))
+	if id == "" || id == "None" {
+		return "", fmt.Errorf("❌ usage plan '%s' is not deployed yet, run 'gozap update' first", plan)
+	}
+	return id, nil
+}
+
+type usagePlanKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func usagePlanKeys(planID string) ([]usagePlanKey, error) {
+	output, err := awsCommand(
+		"apigateway", "get-usage-plan-keys",
+		"--usage-plan-id", planID,
+		"--query", "items",
+		"--output", "json",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage plan keys: %w", err)
+	}
+
+	var keys []usagePlanKey
+	if err := json.Unmarshal(output, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse usage plan keys: %w", err)
+	}
+	return keys, nil
+}
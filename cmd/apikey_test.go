@@ -0,0 +1,124 @@
+package cmd
+
+import "testing"
+
+func TestValidateStageUsagePlans(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "throttle and plans", change: func(c *DeploymentConfig) {
+			c.Throttle = &ThrottleConfig{RateLimit: 100, BurstLimit: 200}
+			c.UsagePlans = []UsagePlan{
+				{Name: "free", Quota: &QuotaConfig{Limit: 1000, Period: "DAY"}, Keys: []string{"demo"}},
+				{Name: "partners", Throttle: &ThrottleConfig{RateLimit: 50, BurstLimit: 100}},
+			}
+		}},
+		{name: "throttle", change: func(c *DeploymentConfig) { c.Throttle = &ThrottleConfig{RateLimit: 10} }, wantErr: "throttling"},
+		{name: "plan throttle", change: func(c *DeploymentConfig) {
+			c.UsagePlans = []UsagePlan{{Name: "partners", Throttle: &ThrottleConfig{BurstLimit: 10}}}
+		}, wantErr: "throttling"},
+		{name: "plan name", change: func(c *DeploymentConfig) { c.UsagePlans = []UsagePlan{{Name: "our partners"}} }, wantErr: "usage plan name"},
+		{name: "quota limit", change: func(c *DeploymentConfig) {
+			c.UsagePlans = []UsagePlan{{Name: "free", Quota: &QuotaConfig{Period: "DAY"}}}
+		}, wantErr: "positive Limit"},
+		{name: "quota period", change: func(c *DeploymentConfig) {
+			c.UsagePlans = []UsagePlan{{Name: "free", Quota: &QuotaConfig{Limit: 10, Period: "YEAR"}}}
+		}, wantErr: "DAY, WEEK or MONTH"},
+		{name: "key name", change: func(c *DeploymentConfig) { c.UsagePlans = []UsagePlan{{Name: "free", Keys: []string{"a/b"}}} }, wantErr: "API key name"},
+		{name: "usage plan twice", change: func(c *DeploymentConfig) {
+			c.UsagePlans = []UsagePlan{{Name: "partners"}, {Name: "partners"}}
+		}, wantErr: "defined twice"},
+	})
+}
+
+func TestSelectUsagePlan(t *testing.T) {
+	one := DeploymentConfig{UsagePlans: []UsagePlan{{Name: "free"}}}
+	two := DeploymentConfig{UsagePlans: []UsagePlan{{Name: "free"}, {Name: "partners"}}}
+	tests := []struct {
+		name    string
+		stage   DeploymentConfig
+		plan    string
+		want    string
+		wantErr bool
+	}{
+		{name: "only plan", stage: one, want: "free"},
+		{name: "named plan", stage: two, plan: "partners", want: "partners"},
+		{name: "several plans", stage: two, wantErr: true},
+		{name: "unknown plan", stage: one, plan: "partners", wantErr: true},
+		{name: "no plans", stage: DeploymentConfig{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectUsagePlan(tt.stage, tt.plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectUsagePlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("selectUsagePlan() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsagePlanTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Throttle = &ThrottleConfig{RateLimit: 100, BurstLimit: 200}
+	stageConfig.UsagePlans = []UsagePlan{
+		{Name: "free", Quota: &QuotaConfig{Limit: 1000, Period: "DAY"}, Keys: []string{"demo"}},
+		{Name: "partners", Throttle: &ThrottleConfig{RateLimit: 50, BurstLimit: 100}},
+	}
+	template := renderTemplate(t, stageConfig)
+
+	var deployment struct {
+		StageDescription struct {
+			MethodSettings []struct {
+				ThrottlingBurstLimit int     `yaml:"ThrottlingBurstLimit"`
+				ThrottlingRateLimit  float64 `yaml:"ThrottlingRateLimit"`
+			} `yaml:"MethodSettings"`
+		} `yaml:"StageDescription"`
+	}
+	template.resource(t, "Deployment"+stageConfig.DeploymentID(), "AWS::ApiGateway::Deployment", &deployment)
+	if settings := deployment.StageDescription.MethodSettings; len(settings) != 1 || settings[0].ThrottlingBurstLimit != 200 || settings[0].ThrottlingRateLimit != 100 {
+		t.Errorf("Deployment MethodSettings = %+v, want the stage throttle", settings)
+	}
+
+	var any0 struct {
+		ApiKeyRequired bool `yaml:"ApiKeyRequired"`
+	}
+	template.resource(t, "ANY0", "AWS::ApiGateway::Method", &any0)
+	if !any0.ApiKeyRequired {
+		t.Error("ANY0 does not require an API key although the stage has usage plans")
+	}
+
+	type usagePlan struct {
+		Quota *struct {
+			Limit  int    `yaml:"Limit"`
+			Period string `yaml:"Period"`
+		} `yaml:"Quota"`
+		Throttle *struct {
+			BurstLimit int     `yaml:"BurstLimit"`
+			RateLimit  float64 `yaml:"RateLimit"`
+		} `yaml:"Throttle"`
+		UsagePlanName string `yaml:"UsagePlanName"`
+	}
+	var free, partners usagePlan
+	template.resource(t, "UsagePlan0", "AWS::ApiGateway::UsagePlan", &free)
+	if free.UsagePlanName != "app-dev-dev-free" || free.Quota == nil || free.Quota.Limit != 1000 || free.Quota.Period != "DAY" || free.Throttle != nil {
+		t.Errorf("UsagePlan0 = %+v, want the free plan with its quota", free)
+	}
+	template.resource(t, "UsagePlan1", "AWS::ApiGateway::UsagePlan", &partners)
+	if partners.Throttle == nil || partners.Throttle.BurstLimit != 100 || partners.Throttle.RateLimit != 50 || partners.Quota != nil {
+		t.Errorf("UsagePlan1 = %+v, want the partners plan with its throttle", partners)
+	}
+
+	var key struct {
+		Name string `yaml:"Name"`
+	}
+	template.resource(t, "UsagePlan0Key0", "AWS::ApiGateway::ApiKey", &key)
+	if key.Name != "app-dev-dev-demo" {
+		t.Errorf("UsagePlan0Key0 Name = %s", key.Name)
+	}
+	template.resource(t, "UsagePlan0Key0Association", "AWS::ApiGateway::UsagePlanKey", nil)
+	if _, ok := template.Resources["UsagePlan1Key0"]; ok {
+		t.Error("partners plan has a key although it lists none")
+	}
+}
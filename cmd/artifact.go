@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// zipEpoch is the fixed modification time written for every zip entry so that
+// the same binary always produces a byte-identical package.
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type Artifact struct {
+	Prefix string // S3 key prefix, defaults to "deployment"
+	Path   string
+	Sha256 string     // hex encoded, used for the S3 key and image tag
+	Files  []zipEntry // packaged files, also copied into container images
+}
+
+// Key returns the content-addressed S3 key for the artifact
+func (a *Artifact) Key() string {
+	prefix := a.Prefix
+	if prefix == "" {
+		prefix = "deployment"
+	}
+	return fmt.Sprintf("%s-%s.zip", prefix, a.Sha256)
+}
+
+// LambdaSha256 returns the hash in the base64 form Lambda reports as CodeSha256
+func (a *Artifact) LambdaSha256() (string, error) {
+	return lambdaSha256(a.Sha256)
+}
+
+// LambdaCodeSha256 returns the deployed artifact hash in Lambda's base64 form
+func (c DeploymentConfig) LambdaCodeSha256() (string, error) {
+	return lambdaSha256(c.CodeSha256)
+}
+
+// lambdaSha256 converts a hex encoded SHA-256 into base64. A malformed hash
+// is an error rather than an empty CodeSha256 in the template.
+func lambdaSha256(hexHash string) (string, error) {
+	sum, err := hex.DecodeString(hexHash)
+	if err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("❌ '%s' is not a hex encoded SHA-256", hexHash)
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// packageArtifact builds the project into binDir and zips it, together with
+// the stage's included files, into a deterministic, content-addressed
+// deployment package
+func packageArtifact(binDir string, stageConfig DeploymentConfig) (*Artifact, error) {
+	if err := buildProject(binDir); err != nil {
+		return nil, err
+	}
+
+	included, err := includedFiles(".", stageConfig.Include)
+	if err != nil {
+		return nil, err
+	}
+	collector, err := collectorEntries(binDir, stageConfig)
+	if err != nil {
+		return nil, err
+	}
+	entries := append([]zipEntry{{Source: filepath.Join(binDir, "bootstrap"), Name: "bootstrap"}}, collector...)
+	entries = append(entries, included...)
+
+	zipFileName := filepath.Join(binDir, "deployment.zip")
+	if err := zipProject(zipFileName, entries); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashFile(zipFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Package SHA-256: %s\n", hash)
+	return &Artifact{Path: zipFileName, Sha256: hash, Files: entries}, nil
+}
+
+func zipProject(zipFileName string, entries []zipEntry) error {
+	fmt.Println("Creating deployment package...")
+	out, err := os.Create(zipFileName)
+	if err != nil {
+		return fmt.Errorf("failed to create zip file: %w", err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	var size int64
+	for _, entry := range entries {
+		n, err := addZipEntry(zw, entry)
+		if err != nil {
+			return fmt.Errorf("failed to zip project: %w", err)
+		}
+		size += n
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to zip project: %w", err)
+	}
+
+	fmt.Printf("Package contains %d file(s), %.1f MB unzipped\n", len(entries), float64(size)/1024/1024)
+	if size > lambdaUnzippedLimit {
+		return fmt.Errorf("❌ unzipped package is %.1f MB, above Lambda's 250 MB limit", float64(size)/1024/1024)
+	}
+	if size > packageSizeWarning {
+		fmt.Printf("Warning: unzipped package is approaching Lambda's 250 MB limit (layers count towards it too)\n")
+	}
+	return nil
+}
+
+// addZipEntry writes one file with a fixed timestamp and normalized mode so
+// the package only depends on file contents. It returns the unzipped size.
+func addZipEntry(zw *zip.Writer, entry zipEntry) (int64, error) {
+	in, err := os.Open(entry.Source)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var mode os.FileMode = 0644
+	if entry.Name == "bootstrap" || info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+
+	header := &zip.FileHeader{
+		Name:     entry.Name,
+		Method:   zip.Deflate,
+		Modified: zipEpoch,
+	}
+	header.SetMode(mode)
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, in)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash '%s': %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// publishArtifact makes the artifact available to Lambda: zip packages are
+// uploaded to S3, container images are built and pushed to ECR
+func publishArtifact(artifact *Artifact, stageConfig DeploymentConfig, binDir string) error {
+	if stageConfig.IsImage() {
+		return pushImage(artifact, stageConfig, binDir)
+	}
+	return uploadArtifact(artifact, stageConfig.S3Bucket)
+}
+
+// uploadArtifact uploads the package unless an object with the same
+// content-addressed key is already in the bucket
+func uploadArtifact(artifact *Artifact, bucket string) error {
+	key := artifact.Key()
+	if s3ObjectExists(bucket, key) {
+		fmt.Printf("Artifact '%s' already exists in S3, skipping upload\n", key)
+		return nil
+	}
+
+	if err := uploadToS3(artifact.Path, bucket, key); err != nil {
+		return err
+	}
+
+	// Wait for the S3 upload to propagate
+	fmt.Println("Waiting for S3 upload to propagate...")
+	return waitForS3Object(bucket, key)
+}
+
+func s3ObjectExists(bucket, key string) bool {
+	cmd := awsCommand("s3api", "head-object", "--bucket", bucket, "--key", key)
+	return cmd.Run() == nil
+}
+
+// deployedTemplate returns the template body the stack was last deployed with
+func deployedTemplate(stackName string) (string, error) {
+	cmd := awsCommand(
+		"cloudformation", "get-template",
+		"--stack-name", stackName,
+		"--query", "TemplateBody",
+		"--output", "text",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get stack template: %w", err)
+	}
+	return string(output), nil
+}
+
+// isNoopDeploy reports whether the code, the stack tags and the rendered
+// template all match what is currently deployed for the stack
+func isNoopDeploy(stackName, functionName, templateFile string, artifact *Artifact, tags map[string]string) bool {
+	deployed, err := deployedArtifact(functionName)
+	if err != nil || deployed.Sha256 != artifact.Sha256 {
+		return false
+	}
+
+	// A stack whose alias is held on another version still has work to do
+	stack, err := describeStack(stackName)
+	if err != nil || !sameTags(stack.stackTags(), tags) || stack.aliasRouting() != (aliasRouting{}) {
+		return false
+	}
+
+	template, err := deployedTemplate(stackName)
+	if err != nil {
+		return false
+	}
+
+	rendered, err := os.ReadFile(templateFile)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(template) == strings.TrimSpace(string(rendered))
+}
+
+// functionDetails is the part of 'aws lambda get-function' artifacts are
+// checked against
+type functionDetails struct {
+	Configuration struct {
+		CodeSha256  string `json:"CodeSha256"`
+		Description string `json:"Description"`
+		PackageType string `json:"PackageType"`
+	} `json:"Configuration"`
+	Code struct {
+		ImageUri string `json:"ImageUri"`
+	} `json:"Code"`
+	Tags map[string]string `json:"Tags"`
+}
+
+func getFunction(functionName string, args ...string) (*functionDetails, error) {
+	cmd := awsCommand(append([]string{"lambda", "get-function", "--function-name", functionName}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function '%s': %w", functionName, err)
+	}
+
+	var response functionDetails
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse function details: %w", err)
+	}
+	return &response, nil
+}
+
+// deployedArtifact looks up the artifact the Lambda function is currently
+// running from the hash tag written at deploy time
+func deployedArtifact(functionName string) (*Artifact, error) {
+	response, err := getFunction(functionName)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{Sha256: response.Tags["gozap:artifact-sha256"]}
+	if artifact.Sha256 == "" {
+		return nil, fmt.Errorf("❌ function '%s' was not deployed from a content-addressed artifact, redeploy it first", functionName)
+	}
+	return artifact, response.check(functionName, artifact)
+}
+
+// versionArtifact looks up the artifact a published version of the function
+// runs from the hash in the version's description. Unlike the function tag,
+// it still names the right artifact while a newer version is being shifted in.
+func versionArtifact(functionName, version string) (*Artifact, error) {
+	response, err := getFunction(functionName, "--qualifier", version)
+	if err != nil {
+		return nil, err
+	}
+
+	sha := describedArtifact(response.Configuration.Description)
+	if sha == "" {
+		return nil, fmt.Errorf("❌ version %s of function '%s' does not record its artifact, redeploy it first", version, functionName)
+	}
+	artifact := &Artifact{Sha256: sha}
+	return artifact, response.check(fmt.Sprintf("%s:%s", functionName, version), artifact)
+}
+
+// describedArtifact returns the artifact hash from a version description
+// written by the template, "Automatically generated with GoZap (<sha256>)",
+// or an empty string when the description records none
+func describedArtifact(description string) string {
+	_, rest, found := strings.Cut(description, "GoZap (")
+	if !found {
+		return ""
+	}
+	sha, _, found := strings.Cut(rest, ")")
+	if !found || len(sha) != 2*sha256.Size {
+		return ""
+	}
+	if _, err := hex.DecodeString(sha); err != nil {
+		return ""
+	}
+	return sha
+}
+
+// check verifies that the function runs the artifact
+func (f *functionDetails) check(functionName string, artifact *Artifact) error {
+	// For images Lambda reports the image digest, so check the tag instead
+	if f.Configuration.PackageType == "Image" {
+		if !strings.HasSuffix(f.Code.ImageUri, ":"+artifact.Sha256) {
+			return fmt.Errorf("❌ image of function '%s' does not match its recorded artifact %s", functionName, artifact.Sha256)
+		}
+		return nil
+	}
+	codeSha256, err := artifact.LambdaSha256()
+	if err != nil {
+		return err
+	}
+	if codeSha256 != f.Configuration.CodeSha256 {
+		return fmt.Errorf("❌ code of function '%s' does not match its recorded artifact %s", functionName, artifact.Sha256)
+	}
+	return nil
+}
+
+// copyArtifact copies an artifact between buckets unless it is already there
+func copyArtifact(artifact *Artifact, fromBucket, toBucket string) error {
+	key := artifact.Key()
+	if s3ObjectExists(toBucket, key) {
+		fmt.Printf("Artifact '%s' already exists in '%s', skipping copy\n", key, toBucket)
+		return nil
+	}
+
+	fmt.Printf("Copying artifact '%s' from '%s' to '%s'...\n", key, fromBucket, toBucket)
+	s3Copy := awsCommand("s3", "cp", fmt.Sprintf("s3://%s/%s", fromBucket, key), fmt.Sprintf("s3://%s/%s", toBucket, key))
+	if output, err := s3Copy.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to copy artifact: %w\n%s", err, output)
+	}
+	return waitForS3Object(toBucket, key)
+}
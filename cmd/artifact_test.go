@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestZipProjectDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	bootstrap := filepath.Join(dir, "bootstrap")
+	config := filepath.Join(dir, "config.yaml")
+	entries := []zipEntry{{Source: bootstrap, Name: "bootstrap"}, {Source: config, Name: "conf/config.yaml"}}
+
+	write := func(mode os.FileMode, modified time.Time) []byte {
+		t.Helper()
+		for _, path := range []string{bootstrap, config} {
+			if err := os.WriteFile(path, []byte("contents of "+filepath.Base(path)), mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+		}
+		zipFile := filepath.Join(t.TempDir(), "deployment.zip")
+		if err := zipProject(zipFile, entries); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(zipFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return content
+	}
+
+	first := write(0600, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	second := write(0644, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if !bytes.Equal(first, second) {
+		t.Fatal("the same files with other modification times and permissions give different packages")
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]os.FileMode{"bootstrap": 0755, "conf/config.yaml": 0644}
+	for _, file := range reader.File {
+		if file.Mode().Perm() != want[file.Name] {
+			t.Errorf("%s has mode %v, want %v", file.Name, file.Mode().Perm(), want[file.Name])
+		}
+		if !file.Modified.Equal(zipEpoch) {
+			t.Errorf("%s was modified %v, want %v", file.Name, file.Modified, zipEpoch)
+		}
+	}
+	if len(reader.File) != len(want) {
+		t.Errorf("package has %d files, want %d", len(reader.File), len(want))
+	}
+
+	// Changing a file changes the package
+	if err := os.WriteFile(config, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	zipFile := filepath.Join(t.TempDir(), "deployment.zip")
+	if err := zipProject(zipFile, entries); err != nil {
+		t.Fatal(err)
+	}
+	if third, _ := os.ReadFile(zipFile); bytes.Equal(first, third) {
+		t.Error("changed contents give the same package")
+	}
+}
+
+func TestArtifactKey(t *testing.T) {
+	sha := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	tests := []struct {
+		artifact Artifact
+		want     string
+	}{
+		{artifact: Artifact{Sha256: sha}, want: "deployment-" + sha + ".zip"},
+		{artifact: Artifact{Prefix: authorizerPrefix, Sha256: sha}, want: authorizerPrefix + "-" + sha + ".zip"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.artifact.Key(); got != tt.want {
+			t.Errorf("Key() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestLambdaSha256(t *testing.T) {
+	tests := []struct {
+		name    string
+		hex     string
+		want    string
+		wantErr bool
+	}{
+		{name: "sha256 of test", hex: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", want: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="},
+		{name: "upper case", hex: "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08", want: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="},
+		{name: "empty", hex: "", wantErr: true},
+		{name: "base64", hex: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=", wantErr: true},
+		{name: "too short", hex: "9f86d081", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lambdaSha256(tt.hex)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lambdaSha256() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lambdaSha256() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribedArtifact(t *testing.T) {
+	sha := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{description: "Automatically generated with GoZap (" + sha + ")", want: sha},
+		{description: "Automatically generated with GoZap ()"},
+		{description: "Automatically generated with GoZap"},
+		{description: "Automatically generated with GoZap (" + sha},
+		{description: "Automatically generated with GoZap (not-a-hash)"},
+		{description: "Hotfix (manual) published from the console"},
+		{description: ""},
+	}
+
+	for _, tt := range tests {
+		if got := describedArtifact(tt.description); got != tt.want {
+			t.Errorf("describedArtifact(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
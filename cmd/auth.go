@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// authorizerPrefix is the S3 key prefix of Lambda authorizer packages. Like
+// function packages they are content-addressed and never expire while current.
+const authorizerPrefix = "deployment-authorizer"
+
+// Path parameters are not supported since API Gateway rejects them next to
+// the {proxy+} resources
+var excludePattern = regexp.MustCompile(`^/([A-Za-z0-9._-]+/)*([A-Za-z0-9._-]+|\*)?$`)
+
+// AuthConfig protects the API endpoints. Paths in Exclude stay public: an
+// exact path such as "/health", or "/docs/*" for a path and everything below.
+type AuthConfig struct {
+	Type        string   // "iam", "cognito" or "lambda"
+	UserPoolArn string   `json:",omitempty"` // cognito
+	Handler     string   `json:",omitempty"` // lambda: package of the authorizer, e.g. ./authorizer
+	Header      string   `json:",omitempty"` // cognito and lambda: header with the token, defaults to Authorization
+	Exclude     []string `json:",omitempty"`
+}
+
+func (a *AuthConfig) validate() error {
+	switch a.Type {
+	case "iam":
+	case "cognito":
+		if !strings.HasPrefix(a.UserPoolArn, "arn:") || !strings.Contains(a.UserPoolArn, ":userpool/") {
+			return fmt.Errorf("❌ cognito auth requires the UserPoolArn of a Cognito user pool")
+		}
+	case "lambda":
+		if !strings.HasPrefix(a.Handler, "./") {
+			return fmt.Errorf("❌ lambda auth requires Handler, the package of the authorizer relative to the project (e.g. ./authorizer)")
+		}
+	default:
+		return fmt.Errorf("❌ Auth Type must be 'iam', 'cognito' or 'lambda', got '%s'", a.Type)
+	}
+
+	if strings.ContainsAny(a.Header, " :'\"") {
+		return fmt.Errorf("❌ '%s' is not a valid header name", a.Header)
+	}
+	for _, exclude := range a.Exclude {
+		if !excludePattern.MatchString(exclude) || len(exclude) > 1 && strings.HasSuffix(exclude, "/") {
+			return fmt.Errorf("❌ invalid auth exclusion '%s', use a path like /health or /docs/*", exclude)
+		}
+	}
+	return nil
+}
+
+// IdentityHeader is the header API Gateway reads the token from
+func (a *AuthConfig) IdentityHeader() string {
+	if a.Header == "" {
+		return "Authorization"
+	}
+	return a.Header
+}
+
+// UsesAuthorizer reports whether the API needs an authorizer resource
+func (a *AuthConfig) UsesAuthorizer() bool {
+	return a.Type == "cognito" || a.Type == "lambda"
+}
+
+// AuthorizerKey returns the S3 key of the Lambda authorizer package
+func (c DeploymentConfig) AuthorizerKey() string {
+	return (&Artifact{Prefix: authorizerPrefix, Sha256: c.AuthorizerCodeSha256}).Key()
+}
+
+// apiResource is an API Gateway resource created for an auth exclusion
+type apiResource struct {
+	Name     string
+	ParentID string
+	PathPart string
+}
+
+// apiMethod is an ANY method proxying requests to the function
+type apiMethod struct {
+	Name              string
+	ResourceID        string
+	AuthorizationType string
+	Authorizer        bool
+	ApiKeyRequired    bool
+}
+
+// ApiResources returns the resources besides the root and its proxy resource
+func (c DeploymentConfig) ApiResources() []apiResource {
+	resources, _ := c.apiRoutes()
+	return resources
+}
+
+// ApiMethods returns the ANY methods of the API with their authorization
+func (c DeploymentConfig) ApiMethods() []apiMethod {
+	_, methods := c.apiRoutes()
+	return methods
+}
+
+// apiRoutes builds the API resources and methods. Every excluded path gets
+// its own resource with a proxy child, so requests below it keep reaching
+// the function with the same authorization as before.
+func (c DeploymentConfig) apiRoutes() ([]apiResource, []apiMethod) {
+	var rootPublic, proxyPublic bool
+	public := map[string]bool{}     // the path itself is public
+	publicTree := map[string]bool{} // everything below the path is public
+	nodes := map[string]bool{}
+
+	if c.Auth != nil {
+		for _, exclude := range c.Auth.Exclude {
+			tree := strings.HasSuffix(exclude, "/*")
+			p := strings.TrimSuffix(exclude, "/*")
+			if p == "" || p == "/" {
+				rootPublic = true
+				proxyPublic = proxyPublic || tree
+				continue
+			}
+
+			public[p] = true
+			publicTree[p] = publicTree[p] || tree
+			for ; p != "/"; p = path.Dir(p) {
+				nodes[p] = true
+			}
+		}
+	}
+
+	methods := []apiMethod{
+		c.apiMethod("ANY0", "!GetAtt Api.RootResourceId", rootPublic),
+		c.apiMethod("ANY1", "!Ref ResourceAnyPathSlashed", proxyPublic),
+	}
+
+	// Sorting puts every parent before its children
+	paths := make([]string, 0, len(nodes))
+	for p := range nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var resources []apiResource
+	names := map[string]string{}
+	for i, p := range paths {
+		name := fmt.Sprintf("Route%d", i)
+		names[p] = name
+
+		parentID := "!GetAtt Api.RootResourceId"
+		if parent := path.Dir(p); parent != "/" {
+			parentID = "!Ref " + names[parent]
+		}
+
+		resources = append(resources,
+			apiResource{Name: name, ParentID: parentID, PathPart: path.Base(p)},
+			apiResource{Name: name + "Proxy", ParentID: "!Ref " + name, PathPart: "{proxy+}"},
+		)
+		methods = append(methods,
+			c.apiMethod(name+"ANY", "!Ref "+name, public[p]),
+			c.apiMethod(name+"ProxyANY", "!Ref "+name+"Proxy", publicTree[p]),
+		)
+	}
+
+	return resources, methods
+}
+
+// apiMethod protects the method with the stage's authorizer and usage plans
+// unless its path is excluded
+func (c DeploymentConfig) apiMethod(name, resourceID string, public bool) apiMethod {
+	method := apiMethod{Name: name, ResourceID: resourceID, AuthorizationType: "NONE"}
+	if public {
+		return method
+	}
+
+	method.ApiKeyRequired = len(c.UsagePlans) > 0
+	if c.Auth == nil {
+		return method
+	}
+
+	switch c.Auth.Type {
+	case "iam":
+		method.AuthorizationType = "AWS_IAM"
+	case "cognito":
+		method.AuthorizationType = "COGNITO_USER_POOLS"
+		method.Authorizer = true
+	case "lambda":
+		method.AuthorizationType = "CUSTOM"
+		method.Authorizer = true
+	}
+	return method
+}
+
+// prepareAuthorizer builds the stage's Lambda authorizer, if it has one, and
+// records its package hash on the stage
+func prepareAuthorizer(binDir string, stageConfig *DeploymentConfig) (*Artifact, error) {
+	if stageConfig.Auth == nil || stageConfig.Auth.Type != "lambda" {
+		return nil, nil
+	}
+
+	fmt.Printf("Packaging Lambda authorizer '%s'...\n", stageConfig.Auth.Handler)
+	binary := filepath.Join(binDir, "authorizer", "bootstrap")
+	if err := buildPackage(stageConfig.Auth.Handler, binary); err != nil {
+		return nil, err
+	}
+
+	zipFileName := filepath.Join(binDir, "authorizer.zip")
+	if err := zipProject(zipFileName, []zipEntry{{Source: binary, Name: "bootstrap"}}); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashFile(zipFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	stageConfig.AuthorizerCodeSha256 = hash
+	return &Artifact{Prefix: authorizerPrefix, Path: zipFileName, Sha256: hash}, nil
+}
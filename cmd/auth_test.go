@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    AuthConfig
+		wantErr bool
+	}{
+		{name: "iam", auth: AuthConfig{Type: "iam"}},
+		{name: "cognito", auth: AuthConfig{Type: "cognito", UserPoolArn: "arn:aws:cognito-idp:us-east-1:123456789012:userpool/us-east-1_abc"}},
+		{name: "cognito without pool", auth: AuthConfig{Type: "cognito"}, wantErr: true},
+		{name: "lambda", auth: AuthConfig{Type: "lambda", Handler: "./authorizer"}},
+		{name: "lambda without handler", auth: AuthConfig{Type: "lambda", Handler: "authorizer"}, wantErr: true},
+		{name: "jwt", auth: AuthConfig{Type: "jwt"}, wantErr: true},
+		{name: "header", auth: AuthConfig{Type: "iam", Header: "X-Token: x"}, wantErr: true},
+		{name: "exclusions", auth: AuthConfig{Type: "iam", Exclude: []string{"/health", "/docs/*", "/"}}},
+		{name: "exclusion with trailing slash", auth: AuthConfig{Type: "iam", Exclude: []string{"/docs/"}}, wantErr: true},
+		{name: "exclusion without slash", auth: AuthConfig{Type: "iam", Exclude: []string{"health"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.auth.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageAuth(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "auth", change: func(c *DeploymentConfig) { c.Auth = &AuthConfig{Type: "iam"} }},
+		{name: "auth type", change: func(c *DeploymentConfig) { c.Auth = &AuthConfig{Type: "basic"} }, wantErr: "Auth Type"},
+	})
+}
+
+func TestApiRoutes(t *testing.T) {
+	stageConfig := validStage()
+	stageConfig.Auth = &AuthConfig{Type: "iam", Exclude: []string{"/health", "/docs/*"}}
+	resources, methods := stageConfig.apiRoutes()
+
+	wantResources := []apiResource{
+		{Name: "Route0", ParentID: "!GetAtt Api.RootResourceId", PathPart: "docs"},
+		{Name: "Route0Proxy", ParentID: "!Ref Route0", PathPart: "{proxy+}"},
+		{Name: "Route1", ParentID: "!GetAtt Api.RootResourceId", PathPart: "health"},
+		{Name: "Route1Proxy", ParentID: "!Ref Route1", PathPart: "{proxy+}"},
+	}
+	if !reflect.DeepEqual(resources, wantResources) {
+		t.Errorf("resources = %+v, want %+v", resources, wantResources)
+	}
+
+	// Only /health itself and everything below /docs stay public
+	want := map[string]string{
+		"ANY0":           "AWS_IAM",
+		"ANY1":           "AWS_IAM",
+		"Route0ANY":      "NONE",
+		"Route0ProxyANY": "NONE",
+		"Route1ANY":      "NONE",
+		"Route1ProxyANY": "AWS_IAM",
+	}
+	if len(methods) != len(want) {
+		t.Fatalf("methods = %+v, want %d", methods, len(want))
+	}
+	for _, method := range methods {
+		if method.AuthorizationType != want[method.Name] {
+			t.Errorf("%s AuthorizationType = %s, want %s", method.Name, method.AuthorizationType, want[method.Name])
+		}
+	}
+}
+
+func TestAuthTemplate(t *testing.T) {
+	type authorizer struct {
+		IdentitySource string   `yaml:"IdentitySource"`
+		ProviderARNs   []string `yaml:"ProviderARNs"`
+		Type           string   `yaml:"Type"`
+	}
+	type method struct {
+		AuthorizationType string `yaml:"AuthorizationType"`
+		AuthorizerId      any    `yaml:"AuthorizerId"`
+	}
+
+	t.Run("cognito", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Auth = &AuthConfig{Type: "cognito", UserPoolArn: "arn:aws:cognito-idp:us-east-1:123456789012:userpool/us-east-1_abc", Header: "X-Token"}
+		template := renderTemplate(t, stageConfig)
+
+		var got authorizer
+		template.resource(t, "Authorizer", "AWS::ApiGateway::Authorizer", &got)
+		if got.Type != "COGNITO_USER_POOLS" || got.IdentitySource != "method.request.header.X-Token" ||
+			!reflect.DeepEqual(got.ProviderARNs, []string{stageConfig.Auth.UserPoolArn}) {
+			t.Errorf("Authorizer = %+v", got)
+		}
+		var any0 method
+		template.resource(t, "ANY0", "AWS::ApiGateway::Method", &any0)
+		if any0.AuthorizationType != "COGNITO_USER_POOLS" || any0.AuthorizerId == nil {
+			t.Errorf("ANY0 = %+v, want it protected by the authorizer", any0)
+		}
+		if _, ok := template.Resources["AuthorizerFunction"]; ok {
+			t.Error("a cognito stage deploys an authorizer function")
+		}
+	})
+
+	t.Run("lambda", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Auth = &AuthConfig{Type: "lambda", Handler: "./authorizer"}
+		stageConfig.AuthorizerCodeSha256 = testSha256
+		template := renderTemplate(t, stageConfig)
+
+		var got authorizer
+		template.resource(t, "Authorizer", "AWS::ApiGateway::Authorizer", &got)
+		if got.Type != "REQUEST" || got.IdentitySource != "method.request.header.Authorization" {
+			t.Errorf("Authorizer = %+v", got)
+		}
+		var function struct {
+			Code struct {
+				S3Key string `yaml:"S3Key"`
+			} `yaml:"Code"`
+			Runtime string `yaml:"Runtime"`
+		}
+		template.resource(t, "AuthorizerFunction", "AWS::Lambda::Function", &function)
+		if function.Code.S3Key != stageConfig.AuthorizerKey() || function.Runtime != "provided.al2" {
+			t.Errorf("AuthorizerFunction = %+v, want the authorizer package on provided.al2", function)
+		}
+	})
+
+	t.Run("iam", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Auth = &AuthConfig{Type: "iam"}
+		template := renderTemplate(t, stageConfig)
+
+		if _, ok := template.Resources["Authorizer"]; ok {
+			t.Error("an iam stage deploys an authorizer")
+		}
+		var any0 method
+		template.resource(t, "ANY0", "AWS::ApiGateway::Method", &any0)
+		if any0.AuthorizationType != "AWS_IAM" || any0.AuthorizerId != nil {
+			t.Errorf("ANY0 = %+v, want AWS_IAM without an authorizer", any0)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed templates/bootstrap.yaml.tmpl
+var bootstrapFS embed.FS
+
+const bootstrapStackName = "gozap-bootstrap"
+
+func NewBootstrapCommand() *cobra.Command {
+	opts := &BootstrapOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Create the managed deployment bucket for a region",
+		Long: `Create (or update) an account and region scoped deployment bucket through a small CloudFormation stack.
+The bucket is versioned, encrypted, blocks public access, only accepts TLS requests and expires replaced
+object versions and uploaded templates. Artifacts are content-addressed and never replaced, so the ones a
+function or a rollback may still need are kept.
+'gozap init' uses it as the default --bucket afterwards. The region comes from --region or the aws CLI default.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBootstrap(opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.RetentionDays, "retention-days", 90, "Days after which replaced object versions and uploaded templates expire")
+
+	return cmd
+}
+
+func runBootstrap(opts *BootstrapOptions) error {
+	// The root --region flag wins over the aws CLI default
+	opts.Region = defaultRegion()
+	if opts.Region == "" {
+		return fmt.Errorf("❌ region is required, pass --region or configure a default region for the aws CLI")
+	}
+	if opts.RetentionDays < 1 {
+		return fmt.Errorf("❌ --retention-days must be at least 1")
+	}
+
+	fmt.Printf("🥾 Bootstrapping GoZap deployment bucket in %s...\n", opts.Region)
+
+	// 1. Render the bootstrap template
+	templateFile := "bootstrap.yaml"
+	defer cleanupFiles([]string{templateFile})
+	if err := generateBootstrapTemplate(templateFile, opts); err != nil {
+		return err
+	}
+
+	// 2. Create or update the bootstrap stack
+	fmt.Printf("Deploying CloudFormation stack '%s'...\n", bootstrapStackName)
+	deploy := awsCommand(
+		"cloudformation", "deploy",
+		"--template-file", templateFile,
+		"--stack-name", bootstrapStackName,
+		"--region", opts.Region,
+		"--no-fail-on-empty-changeset",
+	)
+	if output, err := deploy.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to deploy bootstrap stack: %w\n%s", err, output)
+	}
+
+	// 3. Read the bucket name from the stack outputs
+	describe := awsCommand(
+		"cloudformation", "describe-stacks",
+		"--stack-name", bootstrapStackName,
+		"--region", opts.Region,
+		"--query", "Stacks[0].Outputs[?OutputKey=='BucketName'].OutputValue",
+		"--output", "text",
+	)
+	output, err := describe.Output()
+	if err != nil {
+		return fmt.Errorf("failed to describe bootstrap stack: %w", err)
+	}
+	bucket := strings.TrimSpace(string(output))
+	if bucket == "" {
+		return fmt.Errorf("no bucket found in the outputs of stack '%s'", bootstrapStackName)
+	}
+
+	// 4. Record the bucket so init can default to it
+	if err := recordBootstrapBucket(opts.Region, bucket); err != nil {
+		fmt.Printf("Warning: failed to record bootstrap bucket: %v\n", err)
+	}
+
+	fmt.Printf("✅ Deployment bucket ready: %s\n", bucket)
+	fmt.Println("💡 'gozap init' will use this bucket when --bucket is not given")
+	return nil
+}
+
+func generateBootstrapTemplate(outFile string, opts *BootstrapOptions) error {
+	templateContent, err := bootstrapFS.ReadFile("templates/bootstrap.yaml.tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	tmpl, err := template.New("bootstrap").Parse(string(templateContent))
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	file, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := tmpl.Execute(file, opts); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
+
+// bootstrapRecordPath is where the bootstrapped bucket of each region is
+// recorded. It lives in the user config directory since the buckets are
+// shared by every project of the account.
+func bootstrapRecordPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gozap", "bootstrap.json"), nil
+}
+
+func readBootstrapRecord() (map[string]string, error) {
+	buckets := map[string]string{}
+
+	path, err := bootstrapRecordPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return buckets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return buckets, nil
+}
+
+func recordBootstrapBucket(region, bucket string) error {
+	buckets, err := readBootstrapRecord()
+	if err != nil {
+		return err
+	}
+	buckets[bootstrapKey(region)] = bucket
+
+	path, err := bootstrapRecordPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(buckets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// bootstrapBucket returns the bootstrapped bucket of a region in the current
+// account, if any
+func bootstrapBucket(region string) string {
+	if region == "" {
+		return ""
+	}
+	buckets, err := readBootstrapRecord()
+	if err != nil {
+		return ""
+	}
+	if bucket, found := buckets[bootstrapKey(region)]; found {
+		return bucket
+	}
+	// Buckets recorded before stages could target other accounts
+	return buckets[region]
+}
+
+// bootstrapKey scopes a region to the account of the current target, since
+// stages of a project may live in different accounts
+func bootstrapKey(region string) string {
+	output, err := awsCommand("sts", "get-caller-identity", "--query", "Account", "--output", "text").Output()
+	if account := strings.TrimSpace(string(output)); err == nil && account != "" {
+		return account + "/" + region
+	}
+	return region
+}
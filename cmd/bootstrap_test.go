@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBootstrapLifecycleRules(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "bootstrap.yaml")
+	if err := generateBootstrapTemplate(outFile, &BootstrapOptions{RetentionDays: 30}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type rule struct {
+		Id                          string `yaml:"Id"`
+		Prefix                      string `yaml:"Prefix"`
+		ExpirationInDays            int    `yaml:"ExpirationInDays"`
+		NoncurrentVersionExpiration struct {
+			NoncurrentDays int `yaml:"NoncurrentDays"`
+		} `yaml:"NoncurrentVersionExpiration"`
+	}
+	var template struct {
+		Resources struct {
+			DeploymentBucket struct {
+				Properties struct {
+					LifecycleConfiguration struct {
+						Rules []rule `yaml:"Rules"`
+					} `yaml:"LifecycleConfiguration"`
+				} `yaml:"Properties"`
+			} `yaml:"DeploymentBucket"`
+		} `yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal(content, &template); err != nil {
+		t.Fatalf("bootstrap template is not valid YAML: %v", err)
+	}
+
+	rules := template.Resources.DeploymentBucket.Properties.LifecycleConfiguration.Rules
+	if len(rules) == 0 {
+		t.Fatal("no lifecycle rules")
+	}
+	for _, r := range rules {
+		// Live artifacts must outlive any retention, only templates expire by age
+		if r.ExpirationInDays != 0 && r.Prefix != templatePrefix+"/" {
+			t.Errorf("rule %s expires current objects under %q", r.Id, r.Prefix)
+		}
+		if r.Id == "ExpireReplacedVersions" && r.NoncurrentVersionExpiration.NoncurrentDays != 30 {
+			t.Errorf("replaced versions expire after %d days, want 30", r.NoncurrentVersionExpiration.NoncurrentDays)
+		}
+	}
+}
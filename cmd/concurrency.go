@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxWarmerConcurrency is the number of targets an EventBridge rule can have
+const maxWarmerConcurrency = 5
+
+// WarmerConfig pings the function on a schedule with a synthetic event that
+// scaffolded handlers answer right away, keeping instances warm
+type WarmerConfig struct {
+	Expression  string `json:",omitempty"` // cron(...) or rate(...), defaults to every 5 minutes
+	Concurrency int    `json:",omitempty"` // instances to keep warm, defaults to 1
+}
+
+func (w *WarmerConfig) validate() error {
+	if w.Expression != "" && !strings.HasPrefix(w.Expression, "cron(") && !strings.HasPrefix(w.Expression, "rate(") {
+		return fmt.Errorf("❌ warmer expression '%s' must be cron(...) or rate(...)", w.Expression)
+	}
+	if w.Concurrency < 0 || w.Concurrency > maxWarmerConcurrency {
+		return fmt.Errorf("❌ warmer concurrency must be between 1 and %d, got %d", maxWarmerConcurrency, w.Concurrency)
+	}
+	return nil
+}
+
+// Schedule returns the rule's schedule expression
+func (w *WarmerConfig) Schedule() string {
+	if w.Expression == "" {
+		return "rate(5 minutes)"
+	}
+	return w.Expression
+}
+
+// Targets returns one index per concurrent ping. Each target invokes the
+// alias, and the pings arrive together so they land on separate instances.
+func (w *WarmerConfig) Targets() []int {
+	targets := make([]int, max(w.Concurrency, 1))
+	for i := range targets {
+		targets[i] = i
+	}
+	return targets
+}
+
+// Input returns the synthetic warmer event, quoted for use as a YAML scalar
+func (w *WarmerConfig) Input() string {
+	content, _ := json.Marshal(map[string]any{"gozap": "warmer", "concurrency": max(w.Concurrency, 1)})
+	quoted, _ := json.Marshal(string(content))
+	return string(quoted)
+}
+
+// validateConcurrency checks the reserved and provisioned concurrency of a
+// stage. Provisioned concurrency cannot exceed the reserved concurrency.
+func validateConcurrency(stageConfig DeploymentConfig) error {
+	reserved := stageConfig.ReservedConcurrency
+	if reserved != nil && *reserved < 0 {
+		return fmt.Errorf("❌ ReservedConcurrency must not be negative, got %d", *reserved)
+	}
+	if stageConfig.ProvisionedConcurrency < 0 {
+		return fmt.Errorf("❌ ProvisionedConcurrency must not be negative, got %d", stageConfig.ProvisionedConcurrency)
+	}
+	if reserved != nil && stageConfig.ProvisionedConcurrency > *reserved {
+		return fmt.Errorf("❌ ProvisionedConcurrency (%d) cannot exceed ReservedConcurrency (%d)", stageConfig.ProvisionedConcurrency, *reserved)
+	}
+	if stageConfig.Warmer != nil {
+		if err := stageConfig.Warmer.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestWarmerInput(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		want        float64
+	}{
+		{concurrency: 0, want: 1},
+		{concurrency: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		warmer := &WarmerConfig{Concurrency: tt.concurrency}
+		var content string
+		if err := json.Unmarshal([]byte(warmer.Input()), &content); err != nil {
+			t.Fatalf("Input() is not a quoted string: %v", err)
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(content), &event); err != nil {
+			t.Fatalf("Input() does not hold a JSON event: %v", err)
+		}
+		if event["gozap"] != "warmer" || event["concurrency"] != tt.want {
+			t.Errorf("Input() with Concurrency %d = %v", tt.concurrency, event)
+		}
+		if got := len(warmer.Targets()); got != int(tt.want) {
+			t.Errorf("Targets() with Concurrency %d has %d targets, want %v", tt.concurrency, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStageConcurrency(t *testing.T) {
+	reserved := func(n int) *int { return &n }
+
+	runStageCases(t, []stageCase{
+		{name: "reserved and provisioned", change: func(c *DeploymentConfig) {
+			c.ReservedConcurrency = reserved(10)
+			c.ProvisionedConcurrency = 10
+		}},
+		{name: "no concurrency", change: func(c *DeploymentConfig) { c.ReservedConcurrency = reserved(0) }},
+		{name: "negative reserved", change: func(c *DeploymentConfig) { c.ReservedConcurrency = reserved(-1) }, wantErr: "must not be negative"},
+		{name: "negative provisioned", change: func(c *DeploymentConfig) { c.ProvisionedConcurrency = -1 }, wantErr: "must not be negative"},
+		{name: "provisioned above reserved", change: func(c *DeploymentConfig) {
+			c.ReservedConcurrency = reserved(10)
+			c.ProvisionedConcurrency = 11
+		}, wantErr: "cannot exceed"},
+		{name: "warmer", change: func(c *DeploymentConfig) { c.Warmer = &WarmerConfig{Expression: "rate(10 minutes)", Concurrency: 5} }},
+		{name: "warmer expression", change: func(c *DeploymentConfig) { c.Warmer = &WarmerConfig{Expression: "every 5 minutes"} }, wantErr: "warmer expression"},
+		{name: "warmer concurrency", change: func(c *DeploymentConfig) { c.Warmer = &WarmerConfig{Concurrency: 6} }, wantErr: "warmer concurrency"},
+	})
+}
+
+func TestConcurrencyTemplate(t *testing.T) {
+	reserved := 20
+	stageConfig := deployableStage()
+	stageConfig.ReservedConcurrency = &reserved
+	stageConfig.ProvisionedConcurrency = 2
+	stageConfig.Warmer = &WarmerConfig{Concurrency: 3}
+	template := renderTemplate(t, stageConfig)
+
+	var lambda struct {
+		ReservedConcurrentExecutions int `yaml:"ReservedConcurrentExecutions"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if lambda.ReservedConcurrentExecutions != 20 {
+		t.Errorf("Lambda ReservedConcurrentExecutions = %d, want 20", lambda.ReservedConcurrentExecutions)
+	}
+
+	var alias struct {
+		ProvisionedConcurrencyConfig struct {
+			ProvisionedConcurrentExecutions int `yaml:"ProvisionedConcurrentExecutions"`
+		} `yaml:"ProvisionedConcurrencyConfig"`
+	}
+	template.resource(t, "Alias", "AWS::Lambda::Alias", &alias)
+	if alias.ProvisionedConcurrencyConfig.ProvisionedConcurrentExecutions != 2 {
+		t.Errorf("Alias ProvisionedConcurrentExecutions = %d, want 2", alias.ProvisionedConcurrencyConfig.ProvisionedConcurrentExecutions)
+	}
+
+	var rule struct {
+		ScheduleExpression string `yaml:"ScheduleExpression"`
+		Targets            []struct {
+			Arn   string `yaml:"Arn"`
+			Id    string `yaml:"Id"`
+			Input string `yaml:"Input"`
+		} `yaml:"Targets"`
+	}
+	template.resource(t, "Warmer", "AWS::Events::Rule", &rule)
+	if rule.ScheduleExpression != "rate(5 minutes)" || len(rule.Targets) != 3 {
+		t.Fatalf("Warmer = %+v, want 3 targets every 5 minutes", rule)
+	}
+	for i, target := range rule.Targets {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(target.Input), &event); err != nil || event["gozap"] != "warmer" {
+			t.Errorf("target %d Input = %q, want the warmer event", i, target.Input)
+		}
+		// Every ping must invoke the alias, whose instances serve the traffic
+		if target.Arn != "Alias" || target.Id != fmt.Sprintf("Warmer%d", i) {
+			t.Errorf("target %d = %+v, want Warmer%d invoking the alias", i, target, i)
+		}
+	}
+	template.resource(t, "WarmerPermission", "AWS::Lambda::Permission", nil)
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	defaultCorsMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCorsHeaders = []string{"Content-Type", "Authorization", "X-Amz-Date", "X-Api-Key", "X-Amz-Security-Token"}
+)
+
+// corsMiddlewares are imports of CORS middlewares that would answer the same
+// requests as a stage's Cors block
+var corsMiddlewares = []string{
+	"github.com/gin-contrib/cors",
+	"github.com/rs/cors",
+	"github.com/go-chi/cors",
+	"github.com/gofiber/fiber/v2/middleware/cors",
+}
+
+// CorsConfig lets API Gateway answer CORS preflight requests. Responses of
+// the function get the same headers from the scaffolded dispatcher unless
+// the application sets them itself.
+type CorsConfig struct {
+	AllowOrigins     []string
+	AllowMethods     []string `json:",omitempty"`
+	AllowHeaders     []string `json:",omitempty"`
+	AllowCredentials bool     `json:",omitempty"`
+	MaxAge           int      `json:",omitempty"`
+}
+
+func (c *CorsConfig) validate() error {
+	if len(c.AllowOrigins) == 0 {
+		return fmt.Errorf("❌ Cors requires at least one origin in AllowOrigins")
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("❌ Cors cannot allow credentials for the '*' origin, list the origins instead")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") ||
+			strings.ContainsAny(origin, "\"', ") || strings.HasSuffix(origin, "/") {
+			return fmt.Errorf("❌ '%s' is not a valid CORS origin, use e.g. https://example.com", origin)
+		}
+	}
+	for _, value := range append(c.AllowMethods, c.AllowHeaders...) {
+		if value == "" || strings.ContainsAny(value, "\"', ") {
+			return fmt.Errorf("❌ '%s' is not a valid CORS method or header", value)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("❌ Cors MaxAge cannot be negative, got %d", c.MaxAge)
+	}
+	return nil
+}
+
+// MethodsValue returns the allowed methods as an API Gateway header mapping
+func (c *CorsConfig) MethodsValue() string {
+	if len(c.AllowMethods) == 0 {
+		return headerValue(strings.Join(defaultCorsMethods, ","))
+	}
+	return headerValue(strings.Join(c.AllowMethods, ","))
+}
+
+func (c *CorsConfig) HeadersValue() string {
+	if len(c.AllowHeaders) == 0 {
+		return headerValue(strings.Join(defaultCorsHeaders, ","))
+	}
+	return headerValue(strings.Join(c.AllowHeaders, ","))
+}
+
+// StaticOrigin returns the Allow-Origin header mapping when a single origin
+// is allowed. Several origins are matched against the request instead.
+func (c *CorsConfig) StaticOrigin() string {
+	if len(c.AllowOrigins) != 1 {
+		return ""
+	}
+	return headerValue(c.AllowOrigins[0])
+}
+
+// OriginTemplate returns the mapping template lines that echo the request
+// origin back when it is one of the allowed origins
+func (c *CorsConfig) OriginTemplate() []string {
+	conditions := make([]string, len(c.AllowOrigins))
+	for i, origin := range c.AllowOrigins {
+		conditions[i] = fmt.Sprintf(`$origin == "%s"`, origin)
+	}
+	return []string{
+		`#set($origin = $input.params("Origin"))`,
+		`#if($origin == "")#set($origin = $input.params("origin"))#end`,
+		fmt.Sprintf(`#if(%s)`, strings.Join(conditions, " || ")),
+		`#set($context.responseOverride.header.Access-Control-Allow-Origin = $origin)`,
+		`#end`,
+	}
+}
+
+// OriginsEnv is passed to the function so the dispatcher can add the
+// Allow-Origin header to its responses
+func (c *CorsConfig) OriginsEnv() string {
+	return strings.Join(c.AllowOrigins, ",")
+}
+
+// headerValue quotes a static header value the way API Gateway mappings
+// expect it, as a YAML string
+func headerValue(value string) string {
+	return fmt.Sprintf(`"'%s'"`, value)
+}
+
+// corsMethod is an OPTIONS method answering preflight requests on a resource
+type corsMethod struct {
+	*CorsConfig
+	Name       string
+	ResourceID string
+}
+
+// CorsMethods returns the OPTIONS methods for every resource of the API
+func (c DeploymentConfig) CorsMethods() []corsMethod {
+	if c.Cors == nil {
+		return nil
+	}
+	methods := []corsMethod{
+		{CorsConfig: c.Cors, Name: "OPTIONS0", ResourceID: "!GetAtt Api.RootResourceId"},
+		{CorsConfig: c.Cors, Name: "OPTIONS1", ResourceID: "!Ref ResourceAnyPathSlashed"},
+	}
+	for _, resource := range c.ApiResources() {
+		methods = append(methods, corsMethod{CorsConfig: c.Cors, Name: resource.Name + "OPTIONS", ResourceID: "!Ref " + resource.Name})
+	}
+	return methods
+}
+
+// CorsGatewayResponses maps logical IDs to the API Gateway error responses
+// that get the CORS headers, so browsers can read errors raised before the
+// function runs
+func (c DeploymentConfig) CorsGatewayResponses() map[string]string {
+	return map[string]string{"Cors4XX": "DEFAULT_4XX", "Cors5XX": "DEFAULT_5XX"}
+}
+
+// checkCors warns when both the stage and the application configure CORS
+func checkCors(opts *DoctorOptions) []doctorFinding {
+	imports, err := projectImports(".")
+	if err != nil {
+		return []doctorFinding{{Warning: true, Message: fmt.Sprintf("failed to read Go sources: %v", err)}}
+	}
+
+	var middleware string
+	for _, path := range corsMiddlewares {
+		if imports[path] {
+			middleware = path
+			break
+		}
+	}
+
+	switch {
+	case opts.StageConfig.Cors != nil && middleware != "":
+		return []doctorFinding{{Warning: true, Message: fmt.Sprintf("CORS is configured both in the stage and with %s; API Gateway answers preflight requests, so remove one of them to keep the allowed origins in one place", middleware)}}
+	case opts.StageConfig.Cors != nil:
+		return []doctorFinding{{Message: fmt.Sprintf("API Gateway handles CORS for %s", strings.Join(opts.StageConfig.Cors.AllowOrigins, ", "))}}
+	case middleware != "":
+		return []doctorFinding{{Message: fmt.Sprintf("the application handles CORS with %s", middleware)}}
+	default:
+		return []doctorFinding{{Message: "CORS is not configured"}}
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCorsOriginTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		origins   []string
+		condition string
+	}{
+		{
+			name:      "one origin",
+			origins:   []string{"https://app.example.com"},
+			condition: `#if($origin == "https://app.example.com")`,
+		},
+		{
+			name:      "several origins",
+			origins:   []string{"https://app.example.com", "http://localhost:3000"},
+			condition: `#if($origin == "https://app.example.com" || $origin == "http://localhost:3000")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors := &CorsConfig{AllowOrigins: tt.origins}
+			lines := cors.OriginTemplate()
+			if len(lines) != 5 {
+				t.Fatalf("OriginTemplate() = %d lines, want 5:\n%s", len(lines), strings.Join(lines, "\n"))
+			}
+			if lines[2] != tt.condition {
+				t.Errorf("condition = %s, want %s", lines[2], tt.condition)
+			}
+			// API Gateway passes headers in the case the client sent
+			if !strings.Contains(lines[0], `$input.params("Origin")`) || !strings.Contains(lines[1], `$input.params("origin")`) {
+				t.Errorf("OriginTemplate() does not read both Origin header spellings:\n%s", strings.Join(lines, "\n"))
+			}
+			if lines[3] != `#set($context.responseOverride.header.Access-Control-Allow-Origin = $origin)` || lines[4] != "#end" {
+				t.Errorf("OriginTemplate() does not echo the origin:\n%s", strings.Join(lines, "\n"))
+			}
+		})
+	}
+}
+
+func TestCorsStaticOrigin(t *testing.T) {
+	tests := []struct {
+		origins []string
+		want    string
+	}{
+		{origins: []string{"*"}, want: `"'*'"`},
+		{origins: []string{"https://app.example.com"}, want: `"'https://app.example.com'"`},
+		{origins: []string{"https://a.example.com", "https://b.example.com"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		cors := &CorsConfig{AllowOrigins: tt.origins}
+		if got := cors.StaticOrigin(); got != tt.want {
+			t.Errorf("StaticOrigin() with %v = %s, want %s", tt.origins, got, tt.want)
+		}
+	}
+}
+
+func TestCorsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    CorsConfig
+		wantErr bool
+	}{
+		{name: "any origin", cors: CorsConfig{AllowOrigins: []string{"*"}}},
+		{name: "origins", cors: CorsConfig{AllowOrigins: []string{"https://app.example.com", "http://localhost:3000"}, AllowCredentials: true}},
+		{name: "credentials for any origin", cors: CorsConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}, wantErr: true},
+		{name: "origin without scheme", cors: CorsConfig{AllowOrigins: []string{"app.example.com"}}, wantErr: true},
+		{name: "origin with trailing slash", cors: CorsConfig{AllowOrigins: []string{"https://app.example.com/"}}, wantErr: true},
+		{name: "origin with quote", cors: CorsConfig{AllowOrigins: []string{`https://app.example.com"`}}, wantErr: true},
+		{name: "header with space", cors: CorsConfig{AllowOrigins: []string{"*"}, AllowHeaders: []string{"X Custom"}}, wantErr: true},
+		{name: "negative max age", cors: CorsConfig{AllowOrigins: []string{"*"}, MaxAge: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cors.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageCors(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "cors", change: func(c *DeploymentConfig) { c.Cors = &CorsConfig{AllowOrigins: []string{"https://app.example.com"}} }},
+		{name: "cors without origins", change: func(c *DeploymentConfig) { c.Cors = &CorsConfig{} }, wantErr: "AllowOrigins"},
+	})
+}
+
+func TestCorsTemplate(t *testing.T) {
+	type corsMethod struct {
+		HttpMethod  string `yaml:"HttpMethod"`
+		Integration struct {
+			IntegrationResponses []struct {
+				ResponseParameters map[string]string `yaml:"ResponseParameters"`
+				ResponseTemplates  map[string]string `yaml:"ResponseTemplates"`
+			} `yaml:"IntegrationResponses"`
+			Type string `yaml:"Type"`
+		} `yaml:"Integration"`
+	}
+	type gatewayResponse struct {
+		ResponseParameters map[string]string `yaml:"ResponseParameters"`
+		ResponseType       string            `yaml:"ResponseType"`
+	}
+	t.Run("one origin", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Cors = &CorsConfig{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true, MaxAge: 600}
+		template := renderTemplate(t, stageConfig)
+
+		for _, name := range []string{"OPTIONS0", "OPTIONS1"} {
+			var method corsMethod
+			template.resource(t, name, "AWS::ApiGateway::Method", &method)
+			if method.HttpMethod != "OPTIONS" || method.Integration.Type != "MOCK" || len(method.Integration.IntegrationResponses) != 1 {
+				t.Fatalf("%s = %+v, want a mocked OPTIONS method", name, method)
+			}
+			headers := method.Integration.IntegrationResponses[0].ResponseParameters
+			for header, want := range map[string]string{
+				"Access-Control-Allow-Origin":      "'https://app.example.com'",
+				"Access-Control-Allow-Credentials": "'true'",
+				"Access-Control-Max-Age":           "'600'",
+				"Access-Control-Allow-Methods":     "'" + strings.Join(defaultCorsMethods, ",") + "'",
+			} {
+				if got := headers["method.response.header."+header]; got != want {
+					t.Errorf("%s %s = %q, want %q", name, header, got, want)
+				}
+			}
+		}
+		for name, responseType := range stageConfig.CorsGatewayResponses() {
+			var response gatewayResponse
+			template.resource(t, name, "AWS::ApiGateway::GatewayResponse", &response)
+			if response.ResponseType != responseType || response.ResponseParameters["gatewayresponse.header.Access-Control-Allow-Origin"] != "'https://app.example.com'" {
+				t.Errorf("%s = %+v, want the CORS headers on %s", name, response, responseType)
+			}
+		}
+
+		var lambda struct {
+			Environment struct {
+				Variables map[string]string `yaml:"Variables"`
+			} `yaml:"Environment"`
+		}
+		template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+		if lambda.Environment.Variables["GOZAP_CORS_ORIGINS"] != "https://app.example.com" || lambda.Environment.Variables["GOZAP_CORS_CREDENTIALS"] != "true" {
+			t.Errorf("Lambda environment = %v, want the CORS origins and credentials", lambda.Environment.Variables)
+		}
+		// The deployment must wait for the preflight methods it serves
+		template.resource(t, "Deployment"+stageConfig.DeploymentID(), "AWS::ApiGateway::Deployment", nil)
+		if dependsOn := template.Resources["Deployment"+stageConfig.DeploymentID()].DependsOn; !slices.Contains(dependsOn, "OPTIONS0") || !slices.Contains(dependsOn, "OPTIONS1") {
+			t.Errorf("Deployment DependsOn = %v, want the OPTIONS methods", dependsOn)
+		}
+	})
+
+	t.Run("several origins", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Cors = &CorsConfig{AllowOrigins: []string{"https://a.example.com", "https://b.example.com"}}
+		template := renderTemplate(t, stageConfig)
+
+		var method corsMethod
+		template.resource(t, "OPTIONS0", "AWS::ApiGateway::Method", &method)
+		response := method.Integration.IntegrationResponses[0]
+		if _, ok := response.ResponseParameters["method.response.header.Access-Control-Allow-Origin"]; ok {
+			t.Error("OPTIONS0 has a static Allow-Origin header for several origins")
+		}
+		if response.ResponseParameters["method.response.header.Vary"] != "'Origin'" {
+			t.Error("OPTIONS0 does not vary on the Origin header")
+		}
+		if got, want := response.ResponseTemplates["application/json"], strings.Join(stageConfig.Cors.OriginTemplate(), "\n")+"\n"; got != want {
+			t.Errorf("OPTIONS0 template = %q, want %q", got, want)
+		}
+		if _, ok := template.Resources["Cors4XX"]; ok {
+			t.Error("gateway responses get the CORS headers although the origin depends on the request")
+		}
+	})
+}
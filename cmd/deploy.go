@@ -4,9 +4,7 @@ import (
 	"embed"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -27,6 +25,8 @@ func NewDeployCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().IntVar(&opts.MultiRegion.Parallel, "parallel", 3, "Regions to deploy at the same time when the stage lists several Regions")
+	cmd.Flags().BoolVar(&opts.MultiRegion.ContinueOnError, "continue-on-error", false, "Keep deploying the remaining regions after a region failed")
 	cmd.MarkFlagRequired("stage")
 
 	return cmd
@@ -46,78 +46,111 @@ func runDeploy(opts *DeployOptions) error {
 	if !exists {
 		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.Stage)
 	}
+	stageConfig.Stage = opts.Stage
+
+	// A stage with several Regions is deployed to each of them
+	if fansOut(stageConfig) {
+		return runMultiRegion("deploy", stageConfig, opts.MultiRegion, nil)
+	}
 
-	// 2. Check if CloudFormation stack already exists
+	// Point the aws CLI at the stage's account and region
+	if err := useTarget(&stageConfig); err != nil {
+		return err
+	}
+
+	// 2. Check if S3 bucket exists
+	if err := checkS3Bucket(stageConfig.S3Bucket); err != nil {
+		return err
+	}
+
+	// Acquire the deploy lock for the stage
 	stackName := fmt.Sprintf("%s-%s", stageConfig.FunctionName, opts.Stage)
+	lock, err := acquireLock(stageConfig.S3Bucket, stackName, "deploy")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// 3. Check if CloudFormation stack already exists
 	if err := checkStackExists(stackName); err == nil {
 		return fmt.Errorf("❌ Stack '%s' already exists. Use 'update' command instead", stackName)
 	}
 
-	// Create temporary directories
+	// Create temporary directories. Regions deployed together share the
+	// prebuilt package and work in their own directory.
+	prebuilt, err := prebuiltDir(stageConfig)
+	if err != nil {
+		return err
+	}
 	tempDir := "bin"
+	templateFile := "template.yaml"
+	if prebuilt != "" {
+		tempDir = filepath.Join(prebuilt, currentTarget.Region)
+		templateFile = filepath.Join(tempDir, "template.yaml")
+	}
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
 	// Set up cleanup for local files
-	currentTime := time.Now().Format("20060102150405")
-	zipFileName := fmt.Sprintf("deployment-%s.zip", currentTime)
-	defer cleanupFiles([]string{zipFileName, "template.yaml", tempDir})
+	defer cleanupFiles([]string{templateFile, tempDir})
 
-	// 3. Build the project
-	if err := buildProject(tempDir); err != nil {
+	// 4. Build and package the project
+	artifact, authorizer, err := packageStage(tempDir, &stageConfig, prebuilt)
+	if err != nil {
 		return err
 	}
 
-	// 4. Zip the project
-	if err := zipProject(zipFileName, filepath.Join(tempDir, "bootstrap")); err != nil {
-		return err
+	// Update config with the content-addressed S3Key
+	stageConfig.S3Key = artifact.Key()
+	stageConfig.CodeSha256 = artifact.Sha256
+	if stageConfig.IsImage() {
+		if err := resolveImageUri(&stageConfig, artifact); err != nil {
+			return err
+		}
 	}
 
-	// 5. Check if S3 bucket exists
-	if err := checkS3Bucket(stageConfig.S3Bucket); err != nil {
+	// 5. Upload to S3 or push the image to ECR (skipped if already there)
+	if err := publishArtifact(artifact, stageConfig, tempDir); err != nil {
 		return err
 	}
+	if authorizer != nil {
+		if err := uploadArtifact(authorizer, stageConfig.S3Bucket); err != nil {
+			return err
+		}
+	}
 
-	// Update config with new S3Key
-	stageConfig.S3Key = zipFileName
-	stageConfig.Stage = opts.Stage
-
-	// 6. Upload to S3
-	if err := uploadToS3(zipFileName, stageConfig.S3Bucket, zipFileName); err != nil {
+	// 6. Generate CloudFormation template
+	if err := generateTemplate(templateFile, &stageConfig); err != nil {
 		return err
 	}
-
-	// Wait for the S3 upload to propagate
-	fmt.Println("Waiting for S3 upload to propagate...")
-	if err := waitForS3Object(stageConfig.S3Bucket, zipFileName); err != nil {
+	if err := checkEventBuckets(stackName, stageConfig); err != nil {
 		return err
 	}
 
-	// 7. Generate CloudFormation template
-	if err := generateTemplate("template.yaml", stageConfig); err != nil {
+	// 7. Deploy CloudFormation stack, tagged for cost allocation
+	tags, err := stackTags(stageConfig)
+	if err != nil {
 		return err
 	}
-
-	// 8. Deploy CloudFormation stack
-	if err := deployStack(stackName); err != nil {
+	if err := lock.Check(); err != nil {
+		return err
+	}
+	if err := deployStack(stackName, templateFile, stageConfig.S3Bucket, tags); err != nil {
 		return err
 	}
 
-	// 9. Wait for stack creation to complete
+	// 8. Wait for stack creation to complete
 	if err := waitForStackCreation(stackName); err != nil {
 		return err
 	}
 
-	// 10. Output the stack details
+	// 9. Output the stack details
 	if err := outputStackDetails(stackName); err != nil {
 		return err
 	}
 
-	// 11. NOW it's safe to delete the zip file from S3
-	if err := deleteFromS3(stageConfig.S3Bucket, zipFileName); err != nil {
-		fmt.Printf("Warning: failed to clean up S3 file: %v\n", err)
-	}
+	recordHistory("deploy", stageConfig, stackName)
 
 	fmt.Println("✅ Deployment complete!")
 	return nil
@@ -125,21 +158,29 @@ func runDeploy(opts *DeployOptions) error {
 
 func checkS3Bucket(bucket string) error {
 	fmt.Printf("Checking if S3 bucket '%s' exists...\n", bucket)
-	s3Check := exec.Command("aws", "s3api", "head-bucket", "--bucket", bucket)
+	s3Check := awsCommand("s3api", "head-bucket", "--bucket", bucket)
 	if output, err := s3Check.CombinedOutput(); err != nil {
 		return fmt.Errorf("❌ S3 bucket '%s' does not exist or is not accessible: %w\n%s", bucket, err, output)
 	}
 	return nil
 }
 
-func deployStack(stackName string) error {
+// deployStack creates the stack. The template goes through the deployment
+// bucket, like for updates, so it is not bound to the inline size limit.
+func deployStack(stackName, templateFile, bucket string, tags map[string]string) error {
 	fmt.Printf("Deploying CloudFormation stack '%s'...\n", stackName)
-	deploy := exec.Command(
-		"aws", "cloudformation", "deploy",
-		"--template-file", "template.yaml",
+	args := []string{
+		"cloudformation", "deploy",
+		"--template-file", templateFile,
 		"--stack-name", stackName,
+		"--s3-bucket", bucket,
+		"--s3-prefix", templatePrefix,
 		"--capabilities", "CAPABILITY_NAMED_IAM",
-	)
+	}
+	if len(tags) > 0 {
+		args = append(append(args, "--tags"), deployTagArgs(tags)...)
+	}
+	deploy := awsCommand(args...)
 	if output, err := deploy.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to deploy CloudFormation stack: %w\n%s", err, output)
 	}
@@ -149,8 +190,8 @@ func deployStack(stackName string) error {
 func waitForStackCreation(stackName string) error {
 	fmt.Printf("Waiting for stack '%s' creation to complete...\n", stackName)
 
-	waitCmd := exec.Command(
-		"aws", "cloudformation", "wait", "stack-create-complete",
+	waitCmd := awsCommand(
+		"cloudformation", "wait", "stack-create-complete",
 		"--stack-name", stackName,
 	)
 
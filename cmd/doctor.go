@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorFinding is a single result of a doctor check
+type doctorFinding struct {
+	Warning bool
+	Message string
+}
+
+// doctorCheck inspects one aspect of the project. Checks that need a stage
+// are skipped when doctor runs without --stage.
+type doctorCheck struct {
+	Name       string
+	NeedsStage bool
+	Run        func(opts *DoctorOptions) []doctorFinding
+}
+
+var doctorChecks = []doctorCheck{
+	{Name: "Lambda adapter", Run: checkAdapter},
+	{Name: "VPC", NeedsStage: true, Run: checkVpc},
+	{Name: "CORS", NeedsStage: true, Run: checkCors},
+	{Name: "Tags", NeedsStage: true, Run: checkTags},
+}
+
+func NewDoctorCommand() *cobra.Command {
+	opts := &DoctorOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the project for common problems",
+		Long:  `Check the GoZap project and, with --stage, its stage configuration for common problems before deploying.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project to check (e.g., dev, prod)")
+	cmd.Flags().StringVar(&opts.TagPolicy, "tag-policy", "", "Org tag policy to enforce (default $GOZAP_TAG_POLICY or tag-policy.json)")
+
+	return cmd
+}
+
+func runDoctor(opts *DoctorOptions) error {
+	fmt.Println("🩺 Checking GoZap project...")
+
+	if opts.Stage != "" {
+		config, err := readConfig("config.json")
+		if err != nil {
+			return err
+		}
+		stageConfig, exists := config[opts.Stage]
+		if !exists {
+			return fmt.Errorf("❌ stage '%s' not found in configuration", opts.Stage)
+		}
+		stageConfig.Stage = opts.Stage
+		opts.StageConfig = stageConfig
+		if err := useTarget(&stageConfig); err != nil {
+			return err
+		}
+	}
+
+	warnings := 0
+	for _, check := range doctorChecks {
+		if check.NeedsStage && opts.Stage == "" {
+			continue
+		}
+
+		fmt.Printf("\n%s:\n", check.Name)
+		for _, finding := range check.Run(opts) {
+			if finding.Warning {
+				warnings++
+				fmt.Printf("  ⚠️  %s\n", finding.Message)
+			} else {
+				fmt.Printf("  ✅ %s\n", finding.Message)
+			}
+		}
+	}
+
+	fmt.Println()
+	if warnings > 0 {
+		return fmt.Errorf("❌ doctor found %d problem(s)", warnings)
+	}
+
+	fmt.Println("✅ No problems found")
+	return nil
+}
+
+// checkAdapter reports which Lambda adapter the project's main package imports
+func checkAdapter(opts *DoctorOptions) []doctorFinding {
+	imports, err := projectImports(".")
+	if err != nil {
+		return []doctorFinding{{Warning: true, Message: fmt.Sprintf("failed to read Go sources: %v", err)}}
+	}
+
+	var found []string
+	for framework, adapter := range frameworkAdapters {
+		if imports[adapter] {
+			found = append(found, framework)
+		}
+	}
+	sort.Strings(found)
+
+	switch {
+	case len(found) == 1:
+		return []doctorFinding{{Message: fmt.Sprintf("%s adapter detected", found[0])}}
+	case len(found) > 1:
+		return []doctorFinding{{Warning: true, Message: fmt.Sprintf("multiple adapters imported (%s), only one can handle the Lambda events", strings.Join(found, ", "))}}
+	case imports["github.com/aws/aws-lambda-go/lambda"]:
+		return []doctorFinding{{Message: "no framework adapter found, using a plain aws-lambda-go handler"}}
+	default:
+		return []doctorFinding{{Warning: true, Message: "no Lambda adapter imported, the function will not handle API Gateway events (see 'gozap init --scaffold')"}}
+	}
+}
+
+// projectImports returns the import paths used by the non-test Go files of
+// the package in dir
+func projectImports(dir string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	imports := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := parser.ParseFile(fset, file, src, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, spec := range parsed.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err == nil {
+				imports[path] = true
+			}
+		}
+	}
+
+	return imports, nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var bucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// EventsConfig lists the non-HTTP event sources that invoke the function
+type EventsConfig struct {
+	SQS         []SQSEvent         `json:",omitempty"`
+	SNS         []SNSEvent         `json:",omitempty"`
+	S3          []S3Event          `json:",omitempty"`
+	EventBridge []EventBridgeEvent `json:",omitempty"`
+}
+
+// SQSEvent polls a queue. Without QueueArn the stack creates the queue,
+// optionally with a dead-letter queue. Batches larger than 10 need a batching
+// window, Lambda rejects the mapping otherwise.
+type SQSEvent struct {
+	QueueArn                       string `json:",omitempty"`
+	BatchSize                      int    `json:",omitempty"`
+	MaximumBatchingWindowInSeconds int    `json:",omitempty"`
+	DeadLetterQueue                bool   `json:",omitempty"`
+	MaxReceiveCount                int    `json:",omitempty"`
+}
+
+type SNSEvent struct {
+	TopicArn     string
+	FilterPolicy json.RawMessage `json:",omitempty"`
+}
+
+// S3Event creates a bucket in the stack that notifies the function.
+// CloudFormation can only configure notifications on buckets it creates, so
+// existing buckets are rejected; they can send their events through
+// EventBridge instead.
+type S3Event struct {
+	Bucket string
+	Events []string `json:",omitempty"`
+	Prefix string   `json:",omitempty"`
+	Suffix string   `json:",omitempty"`
+}
+
+type EventBridgeEvent struct {
+	EventBusName string `json:",omitempty"`
+	Pattern      json.RawMessage
+}
+
+func (e *EventsConfig) validate() error {
+	for _, q := range e.SQS {
+		if q.QueueArn != "" && q.DeadLetterQueue {
+			return fmt.Errorf("❌ a dead-letter queue can only be configured for queues created by GoZap, configure it on '%s' directly", q.QueueArn)
+		}
+		if q.BatchSize < 0 || q.BatchSize > 10000 {
+			return fmt.Errorf("❌ SQS BatchSize must be between 1 and 10000, or omitted for the default of 10, got %d", q.BatchSize)
+		}
+		if q.MaximumBatchingWindowInSeconds < 0 || q.MaximumBatchingWindowInSeconds > 300 {
+			return fmt.Errorf("❌ SQS MaximumBatchingWindowInSeconds must be between 1 and 300, or omitted for no window, got %d", q.MaximumBatchingWindowInSeconds)
+		}
+		if q.BatchSize > 10 && q.MaximumBatchingWindowInSeconds == 0 {
+			return fmt.Errorf("❌ SQS BatchSize %d is above 10 and requires MaximumBatchingWindowInSeconds", q.BatchSize)
+		}
+	}
+	for _, t := range e.SNS {
+		if t.TopicArn == "" {
+			return fmt.Errorf("❌ SNS events require a TopicArn")
+		}
+		if len(t.FilterPolicy) > 0 && !json.Valid(t.FilterPolicy) {
+			return fmt.Errorf("❌ filter policy of topic '%s' is not valid JSON", t.TopicArn)
+		}
+	}
+	for _, b := range e.S3 {
+		if b.Bucket == "" {
+			return fmt.Errorf("❌ S3 events require a Bucket")
+		}
+		if !bucketName.MatchString(b.Bucket) {
+			return fmt.Errorf("❌ S3 event Bucket '%s' must be the name of a bucket for the stack to create, use an EventBridge event for existing buckets", b.Bucket)
+		}
+	}
+	for _, r := range e.EventBridge {
+		if len(r.Pattern) == 0 || !json.Valid(r.Pattern) {
+			return fmt.Errorf("❌ EventBridge events require a valid JSON Pattern")
+		}
+	}
+	return nil
+}
+
+// Batch returns the batch size, defaulting to the SQS default of 10
+func (q SQSEvent) Batch() int {
+	if q.BatchSize == 0 {
+		return 10
+	}
+	return q.BatchSize
+}
+
+func (q SQSEvent) ReceiveCount() int {
+	if q.MaxReceiveCount == 0 {
+		return 5
+	}
+	return q.MaxReceiveCount
+}
+
+func (t SNSEvent) FilterPolicyJSON() string {
+	return compactJSON(t.FilterPolicy)
+}
+
+func (b S3Event) EventTypes() []string {
+	if len(b.Events) == 0 {
+		return []string{"s3:ObjectCreated:*"}
+	}
+	return b.Events
+}
+
+func (r EventBridgeEvent) PatternJSON() string {
+	return compactJSON(r.Pattern)
+}
+
+// QueueVisibilityTimeout follows the AWS recommendation of six times the
+// function timeout for queues consumed by Lambda
+func (c DeploymentConfig) QueueVisibilityTimeout() int {
+	return min(6*c.Timeout, 43200)
+}
+
+// compactJSON renders JSON on one line, which is also valid YAML
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "{}"
+	}
+	return buf.String()
+}
+
+// checkEventBuckets fails when an S3 event names a bucket that exists outside
+// the stack, e.g. one created by hand or retained by an earlier undeploy.
+// CloudFormation would fail on it halfway through the deployment.
+func checkEventBuckets(stackName string, stageConfig DeploymentConfig) error {
+	if stageConfig.Events == nil || len(stageConfig.Events.S3) == 0 {
+		return nil
+	}
+
+	// Buckets the stack already created are fine
+	output, _ := awsCommand(
+		"cloudformation", "describe-stack-resources",
+		"--stack-name", stackName,
+		"--query", "StackResources[?ResourceType=='AWS::S3::Bucket'].PhysicalResourceId",
+		"--output", "text",
+	).Output()
+	owned := strings.Fields(string(output))
+
+	for _, b := range stageConfig.Events.S3 {
+		if slices.Contains(owned, b.Bucket) {
+			continue
+		}
+		// A bucket of another account answers 403, a free name 404
+		headOutput, err := awsCommand("s3api", "head-bucket", "--bucket", b.Bucket).CombinedOutput()
+		if err == nil || strings.Contains(string(headOutput), "403") {
+			return fmt.Errorf("❌ S3 bucket '%s' already exists outside the stack. S3 events need a bucket the stage creates; "+
+				"for an existing bucket, enable its EventBridge notifications and add an EventBridge event with source aws.s3", b.Bucket)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeAWS puts a shell script first on the PATH in place of the aws CLI
+func fakeAWS(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake aws CLI is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "aws"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	tests := []struct {
+		timeout int
+		want    int
+	}{
+		{timeout: 1, want: 6},
+		{timeout: 30, want: 180},
+		{timeout: 900, want: 5400},
+	}
+
+	for _, tt := range tests {
+		c := DeploymentConfig{Timeout: tt.timeout}
+		if got := c.QueueVisibilityTimeout(); got != tt.want {
+			t.Errorf("QueueVisibilityTimeout() with Timeout %d = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestEventsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  EventsConfig
+		wantErr bool
+	}{
+		{name: "default batch", events: EventsConfig{SQS: []SQSEvent{{}}}},
+		{name: "batch of 10", events: EventsConfig{SQS: []SQSEvent{{BatchSize: 10}}}},
+		{name: "large batch with window", events: EventsConfig{SQS: []SQSEvent{{BatchSize: 10000, MaximumBatchingWindowInSeconds: 300}}}},
+		{name: "large batch without window", events: EventsConfig{SQS: []SQSEvent{{BatchSize: 11}}}, wantErr: true},
+		{name: "batch too large", events: EventsConfig{SQS: []SQSEvent{{BatchSize: 10001, MaximumBatchingWindowInSeconds: 1}}}, wantErr: true},
+		{name: "negative batch", events: EventsConfig{SQS: []SQSEvent{{BatchSize: -1}}}, wantErr: true},
+		{name: "window too long", events: EventsConfig{SQS: []SQSEvent{{MaximumBatchingWindowInSeconds: 301}}}, wantErr: true},
+		{name: "dead-letter queue of an existing queue", events: EventsConfig{SQS: []SQSEvent{{
+			QueueArn: "arn:aws:sqs:us-east-1:123456789012:orders", DeadLetterQueue: true,
+		}}}, wantErr: true},
+		{name: "sns without topic", events: EventsConfig{SNS: []SNSEvent{{}}}, wantErr: true},
+		{name: "sns filter policy", events: EventsConfig{SNS: []SNSEvent{{
+			TopicArn: "arn:aws:sns:us-east-1:123456789012:alerts", FilterPolicy: json.RawMessage(`{"severity":`),
+		}}}, wantErr: true},
+		{name: "s3 without bucket", events: EventsConfig{S3: []S3Event{{}}}, wantErr: true},
+		{name: "s3 bucket", events: EventsConfig{S3: []S3Event{{Bucket: "app-prod-uploads.example"}}}},
+		{name: "s3 bucket arn", events: EventsConfig{S3: []S3Event{{Bucket: "arn:aws:s3:::uploads"}}}, wantErr: true},
+		{name: "s3 bucket in upper case", events: EventsConfig{S3: []S3Event{{Bucket: "Uploads"}}}, wantErr: true},
+		{name: "eventbridge pattern", events: EventsConfig{EventBridge: []EventBridgeEvent{{Pattern: json.RawMessage(`{"source":["x"]}`)}}}},
+		{name: "eventbridge without pattern", events: EventsConfig{EventBridge: []EventBridgeEvent{{}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.events.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageEvents(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "sqs batch above 10 with window", change: func(c *DeploymentConfig) {
+			c.Events = &EventsConfig{SQS: []SQSEvent{{BatchSize: 100, MaximumBatchingWindowInSeconds: 5}}}
+		}},
+		{name: "sqs batch above 10 without window", change: func(c *DeploymentConfig) {
+			c.Events = &EventsConfig{SQS: []SQSEvent{{BatchSize: 100}}}
+		}, wantErr: "MaximumBatchingWindowInSeconds"},
+		{name: "existing s3 bucket", change: func(c *DeploymentConfig) {
+			c.Events = &EventsConfig{S3: []S3Event{{Bucket: "arn:aws:s3:::uploads"}}}
+		}, wantErr: "EventBridge event for existing buckets"},
+	})
+}
+
+func TestEventsTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Events = &EventsConfig{
+		SQS: []SQSEvent{
+			{DeadLetterQueue: true, MaxReceiveCount: 3},
+			{QueueArn: "arn:aws:sqs:us-east-1:123456789012:orders", BatchSize: 100, MaximumBatchingWindowInSeconds: 5},
+		},
+		SNS: []SNSEvent{{TopicArn: "arn:aws:sns:us-east-1:123456789012:signups", FilterPolicy: json.RawMessage(`{"plan": ["pro"]}`)}},
+		S3:  []S3Event{{Bucket: "app-dev-uploads", Prefix: "incoming/", Suffix: ".csv"}},
+		EventBridge: []EventBridgeEvent{
+			{Pattern: json.RawMessage(`{"source": ["aws.ec2"], "detail-type": ["EC2 Instance State-change Notification"]}`)},
+		},
+	}
+	template := renderTemplate(t, stageConfig)
+
+	var queue struct {
+		RedrivePolicy struct {
+			MaxReceiveCount int `yaml:"maxReceiveCount"`
+		} `yaml:"RedrivePolicy"`
+		VisibilityTimeout int `yaml:"VisibilityTimeout"`
+	}
+	template.resource(t, "Queue0", "AWS::SQS::Queue", &queue)
+	if queue.RedrivePolicy.MaxReceiveCount != 3 || queue.VisibilityTimeout != stageConfig.QueueVisibilityTimeout() {
+		t.Errorf("Queue0 = %+v", queue)
+	}
+	template.resource(t, "Queue0DeadLetter", "AWS::SQS::Queue", nil)
+
+	type mapping struct {
+		BatchSize                      int    `yaml:"BatchSize"`
+		EventSourceArn                 string `yaml:"EventSourceArn"`
+		MaximumBatchingWindowInSeconds int    `yaml:"MaximumBatchingWindowInSeconds"`
+	}
+	var created, existing mapping
+	template.resource(t, "Queue0Mapping", "AWS::Lambda::EventSourceMapping", &created)
+	if created.BatchSize != 10 || created.MaximumBatchingWindowInSeconds != 0 {
+		t.Errorf("Queue0Mapping = %+v, want the default batch without a window", created)
+	}
+	template.resource(t, "Queue1Mapping", "AWS::Lambda::EventSourceMapping", &existing)
+	if existing.BatchSize != 100 || existing.MaximumBatchingWindowInSeconds != 5 || existing.EventSourceArn != stageConfig.Events.SQS[1].QueueArn {
+		t.Errorf("Queue1Mapping = %+v, want a batch of 100 within 5 seconds from the existing queue", existing)
+	}
+	if _, ok := template.Resources["Queue1"]; ok {
+		t.Error("Queue1 is created although the event names an existing queue")
+	}
+
+	var subscription struct {
+		FilterPolicy map[string][]string `yaml:"FilterPolicy"`
+	}
+	template.resource(t, "Topic0Subscription", "AWS::SNS::Subscription", &subscription)
+	if len(subscription.FilterPolicy["plan"]) != 1 {
+		t.Errorf("Topic0Subscription FilterPolicy = %v", subscription.FilterPolicy)
+	}
+	template.resource(t, "Topic0Permission", "AWS::Lambda::Permission", nil)
+
+	var bucket struct {
+		BucketName string `yaml:"BucketName"`
+	}
+	template.resource(t, "Bucket0", "AWS::S3::Bucket", &bucket)
+	if bucket.BucketName != "app-dev-uploads" {
+		t.Errorf("Bucket0 BucketName = %q", bucket.BucketName)
+	}
+	template.resource(t, "Bucket0Permission", "AWS::Lambda::Permission", nil)
+
+	var rule struct {
+		EventPattern map[string][]string `yaml:"EventPattern"`
+	}
+	template.resource(t, "EventRule0", "AWS::Events::Rule", &rule)
+	if len(rule.EventPattern["source"]) != 1 {
+		t.Errorf("EventRule0 EventPattern = %v", rule.EventPattern)
+	}
+	template.resource(t, "EventRule0Permission", "AWS::Lambda::Permission", nil)
+}
+
+func TestCheckEventBuckets(t *testing.T) {
+	fakeAWS(t, `case "$1 $2" in
+  "cloudformation describe-stack-resources") echo "owned-bucket" ;;
+  "s3api head-bucket")
+    case $4 in
+      owned-bucket|taken-bucket) exit 0 ;;
+      foreign-bucket) echo "An error occurred (403) when calling the HeadBucket operation: Forbidden" >&2; exit 254 ;;
+      *) echo "An error occurred (404) when calling the HeadBucket operation: Not Found" >&2; exit 254 ;;
+    esac ;;
+  *) exit 1 ;;
+esac
+`)
+
+	tests := []struct {
+		bucket  string
+		wantErr bool
+	}{
+		{bucket: "owned-bucket"},
+		{bucket: "free-bucket"},
+		{bucket: "taken-bucket", wantErr: true},
+		{bucket: "foreign-bucket", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			stageConfig := validStage()
+			stageConfig.Events = &EventsConfig{S3: []S3Event{{Bucket: tt.bucket}}}
+			err := checkEventBuckets("app-dev", stageConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkEventBuckets() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "EventBridge") {
+				t.Errorf("checkEventBuckets() = %v, want it to point to EventBridge", err)
+			}
+		})
+	}
+}
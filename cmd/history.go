@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const historyFile = ".gozap/history.jsonl"
+
+type HistoryEntry struct {
+	Timestamp      time.Time
+	Stage          string
+	Region         string            `json:",omitempty"`
+	Action         string            // deploy, update, promote, abort, undeploy
+	ArtifactSha256 string            `json:",omitempty"`
+	S3Key          string            `json:",omitempty"`
+	GitSha         string            `json:",omitempty"`
+	GitDirty       bool              `json:",omitempty"`
+	User           string            `json:",omitempty"`
+	CallerArn      string            `json:",omitempty"`
+	StackStatus    string            `json:",omitempty"`
+	Outputs        map[string]string `json:",omitempty"`
+}
+
+func NewHistoryCommand() *cobra.Command {
+	opts := &HistoryOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List past deployments of a stage",
+		Long:  `List the deployments, updates, promotions and rollbacks recorded for a stage in .gozap/history.jsonl.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "l", 20, "Maximum number of entries to show (0 for all)")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func runHistory(opts *HistoryOptions) error {
+	entries, err := readHistory(historyFile, opts.Stage)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No deployments recorded for stage '%s'\n", opts.Stage)
+		return nil
+	}
+
+	// Keep the most recent entries, newest printed first
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[len(entries)-opts.Limit:]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tREGION\tARTIFACT\tGIT\tUSER\tSTATUS")
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		gitSha := shortHash(entry.GitSha)
+		if entry.GitDirty {
+			gitSha += " (dirty)"
+		}
+		region := entry.Region
+		if region == "" {
+			region = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			entry.Action,
+			region,
+			shortHash(entry.ArtifactSha256),
+			gitSha,
+			entry.User,
+			entry.StackStatus,
+		)
+	}
+	return w.Flush()
+}
+
+// recordHistory appends an entry for a completed action on a stage. Failing to
+// record history never fails the action itself.
+func recordHistory(action string, stageConfig DeploymentConfig, stackName string) {
+	entry := HistoryEntry{
+		Timestamp:      time.Now().UTC(),
+		Stage:          stageConfig.Stage,
+		Action:         action,
+		ArtifactSha256: stageConfig.CodeSha256,
+		S3Key:          stageConfig.S3Key,
+		CallerArn:      callerArn(),
+		Region:         currentTarget.Region,
+	}
+
+	// Alias-only actions don't carry an artifact, look up what is running
+	if entry.ArtifactSha256 == "" {
+		if artifact, err := deployedArtifact(stackName); err == nil {
+			entry.ArtifactSha256 = artifact.Sha256
+			entry.S3Key = artifact.Key()
+		}
+	}
+
+	entry.GitSha, entry.GitDirty = gitRevision()
+
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+
+	if stack, err := describeStack(stackName); err == nil {
+		entry.StackStatus = stack.StackStatus
+		entry.Outputs = stack.stackOutputs()
+	}
+
+	if err := appendHistory(historyFile, entry); err != nil {
+		fmt.Printf("Warning: failed to record deployment history: %v\n", err)
+	}
+}
+
+func appendHistory(path string, entry HistoryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// readHistory returns the entries recorded for a stage, oldest first
+func readHistory(path, stage string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history file: %w", err)
+		}
+		if entry.Stage == stage {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// gitRevision returns the current commit and whether the work tree has
+// uncommitted changes. Both are empty outside a git repository.
+func gitRevision() (string, bool) {
+	sha, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+
+	status, err := exec.Command("git", "status", "--porcelain").Output()
+	dirty := err == nil && len(strings.TrimSpace(string(status))) > 0
+
+	return strings.TrimSpace(string(sha)), dirty
+}
+
+func callerArn() string {
+	cmd := awsCommand("sts", "get-caller-identity", "--query", "Arn", "--output", "text")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed templates/Dockerfile.tmpl
+var dockerfileFS embed.FS
+
+// IsImage reports whether the stage is deployed as a container image
+func (c DeploymentConfig) IsImage() bool {
+	return c.Package == "image"
+}
+
+// imageRepositoryName returns the ECR repository for the project. All stages
+// share it so promoted images do not have to be copied.
+func imageRepositoryName(stageConfig DeploymentConfig) string {
+	project := strings.TrimSuffix(stageConfig.FunctionName, "-"+stageConfig.Stage)
+	return "gozap/" + strings.ToLower(project)
+}
+
+// resolveImageUri ensures the ECR repository exists and sets the
+// content-addressed image URI for the artifact on the stage
+func resolveImageUri(stageConfig *DeploymentConfig, artifact *Artifact) error {
+	repositoryUri, err := ensureRepository(imageRepositoryName(*stageConfig))
+	if err != nil {
+		return err
+	}
+	stageConfig.ImageUri = fmt.Sprintf("%s:%s", repositoryUri, artifact.Sha256)
+	return nil
+}
+
+// ensureRepository returns the URI of the ECR repository, creating it first
+// if it does not exist
+func ensureRepository(name string) (string, error) {
+	describe := awsCommand(
+		"ecr", "describe-repositories",
+		"--repository-names", name,
+		"--query", "repositories[0].repositoryUri",
+		"--output", "text",
+	)
+	if output, err := describe.Output(); err == nil {
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	fmt.Printf("Creating ECR repository '%s'...\n", name)
+	create := awsCommand(
+		"ecr", "create-repository",
+		"--repository-name", name,
+		"--image-tag-mutability", "IMMUTABLE",
+		"--image-scanning-configuration", "scanOnPush=true",
+		"--query", "repository.repositoryUri",
+		"--output", "text",
+	)
+	output, err := create.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to create ECR repository '%s': %w\n%s", name, err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func imageExists(repository, tag string) bool {
+	cmd := awsCommand("ecr", "describe-images", "--repository-name", repository, "--image-ids", "imageTag="+tag)
+	return cmd.Run() == nil
+}
+
+// pushImage builds a container image from the packaged files and pushes it
+// to the stage's ImageUri unless an image with the same tag already exists
+func pushImage(artifact *Artifact, stageConfig DeploymentConfig, binDir string) error {
+	repository := imageRepositoryName(stageConfig)
+	if imageExists(repository, artifact.Sha256) {
+		fmt.Printf("Image '%s' already exists in ECR, skipping push\n", stageConfig.ImageUri)
+		return nil
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("❌ docker is required to build container images: %w", err)
+	}
+
+	contextDir := filepath.Join(binDir, "image")
+	if err := prepareImageContext(contextDir, artifact.Files); err != nil {
+		return err
+	}
+
+	dockerfile, err := dockerfileFS.ReadFile("templates/Dockerfile.tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to read Dockerfile template: %w", err)
+	}
+	dockerfilePath := filepath.Join(binDir, "Dockerfile")
+	if err := os.WriteFile(dockerfilePath, dockerfile, 0644); err != nil {
+		return fmt.Errorf("failed to write Dockerfile: %w", err)
+	}
+
+	fmt.Printf("Building image '%s'...\n", stageConfig.ImageUri)
+	// Lambda rejects the multi-platform index buildx creates for provenance
+	build := exec.Command(
+		"docker", "build",
+		"--platform", "linux/amd64",
+		"--provenance=false",
+		"--file", dockerfilePath,
+		"--tag", stageConfig.ImageUri,
+		contextDir,
+	)
+	if output, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build image: %w\n%s", err, output)
+	}
+
+	registry, _, _ := strings.Cut(stageConfig.ImageUri, "/")
+	if err := ecrLogin(registry); err != nil {
+		return err
+	}
+
+	fmt.Println("Pushing image to ECR...")
+	push := exec.Command("docker", "push", stageConfig.ImageUri)
+	if output, err := push.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push image: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// prepareImageContext copies the packaged files into the docker build context
+func prepareImageContext(contextDir string, files []zipEntry) error {
+	for _, file := range files {
+		target := filepath.Join(contextDir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create image context: %w", err)
+		}
+		if err := copyFile(file.Source, target); err != nil {
+			return fmt.Errorf("failed to copy '%s' into image context: %w", file.Source, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ecrLogin authenticates docker against the ECR registry
+func ecrLogin(registry string) error {
+	password, err := awsCommand("ecr", "get-login-password").Output()
+	if err != nil {
+		return fmt.Errorf("failed to get ECR login password: %w", err)
+	}
+
+	login := exec.Command("docker", "login", "--username", "AWS", "--password-stdin", registry)
+	login.Stdin = bytes.NewReader(password)
+	if output, err := login.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to log in to ECR registry '%s': %w\n%s", registry, err, output)
+	}
+	return nil
+}
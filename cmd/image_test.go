@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateStagePackage(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "zip", change: func(c *DeploymentConfig) { c.Package = "zip" }},
+		{name: "image", change: func(c *DeploymentConfig) { c.Package = "image" }},
+		{name: "unknown package", change: func(c *DeploymentConfig) { c.Package = "jar" }, wantErr: "Package"},
+		{name: "image with layers", change: func(c *DeploymentConfig) {
+			c.Package = "image"
+			c.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:assets:1"}
+		}, wantErr: "layers"},
+	})
+}
+
+func TestImageRepositoryName(t *testing.T) {
+	stageConfig := DeploymentConfig{FunctionName: "My-Shop-prod", Stage: "prod"}
+	if got := imageRepositoryName(stageConfig); got != "gozap/my-shop" {
+		t.Errorf("imageRepositoryName() = %s, want gozap/my-shop", got)
+	}
+}
+
+// The image must run on the same OS as zip functions so switching a stage
+// between the two does not change its system libraries
+func TestImageBaseMatchesRuntime(t *testing.T) {
+	dockerfile, err := dockerfileFS.ReadFile("templates/Dockerfile.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var base string
+	scanner := bufio.NewScanner(bytes.NewReader(dockerfile))
+	for scanner.Scan() {
+		if from, ok := strings.CutPrefix(scanner.Text(), "FROM "); ok {
+			base = strings.TrimSpace(from)
+		}
+	}
+
+	var lambda struct {
+		Runtime string `yaml:"Runtime"`
+	}
+	renderTemplate(t, deployableStage()).resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if want := "public.ecr.aws/lambda/provided:" + strings.TrimPrefix(lambda.Runtime, "provided."); base != want {
+		t.Errorf("Dockerfile is based on %q, want %q to match the %s runtime", base, want, lambda.Runtime)
+	}
+}
+
+func TestImageTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Package = "image"
+	stageConfig.ImageUri = "123456789012.dkr.ecr.us-east-1.amazonaws.com/gozap/app:" + testSha256
+	template := renderTemplate(t, stageConfig)
+
+	var lambda struct {
+		Code        map[string]string `yaml:"Code"`
+		Handler     string            `yaml:"Handler"`
+		PackageType string            `yaml:"PackageType"`
+		Runtime     string            `yaml:"Runtime"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if len(lambda.Code) != 1 || lambda.Code["ImageUri"] != stageConfig.ImageUri {
+		t.Errorf("Lambda Code = %v, want only the ImageUri", lambda.Code)
+	}
+	if lambda.PackageType != "Image" || lambda.Runtime != "" || lambda.Handler != "" {
+		t.Errorf("Lambda = %+v, want an image function without a runtime or handler", lambda)
+	}
+
+	var version map[string]any
+	template.resource(t, "Version"+stageConfig.VersionID(), "AWS::Lambda::Version", &version)
+	if _, ok := version["CodeSha256"]; ok {
+		t.Error("Version of an image function has a CodeSha256")
+	}
+}
+
+func TestPrepareImageContext(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "bootstrap")
+	if err := os.WriteFile(source, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	contextDir := filepath.Join(t.TempDir(), "image")
+	if err := prepareImageContext(contextDir, []zipEntry{{Source: source, Name: "bootstrap"}, {Source: source, Name: "bin/tool"}}); err != nil {
+		t.Fatalf("prepareImageContext() error = %v", err)
+	}
+	for _, name := range []string{"bootstrap", "bin/tool"} {
+		info, err := os.Stat(filepath.Join(contextDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s is missing from the context: %v", name, err)
+			continue
+		}
+		if info.Mode().Perm()&0100 == 0 {
+			t.Errorf("%s lost its executable bit", name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Lambda rejects functions whose unzipped code and layers exceed 250 MB
+const (
+	lambdaUnzippedLimit = 250 * 1024 * 1024
+	packageSizeWarning  = lambdaUnzippedLimit * 8 / 10
+)
+
+// IncludeRule copies the project files matching Source (a glob relative to
+// the project root, "**" matches any number of directories) into the
+// package, under the optional Destination prefix. In config.json a rule can
+// also be written as a plain glob string.
+type IncludeRule struct {
+	Source      string
+	Destination string `json:",omitempty"`
+}
+
+func (r *IncludeRule) UnmarshalJSON(data []byte) error {
+	var source string
+	if err := json.Unmarshal(data, &source); err == nil {
+		r.Source = source
+		return nil
+	}
+
+	type rule IncludeRule
+	return json.Unmarshal(data, (*rule)(r))
+}
+
+// zipEntry is a file added to the deployment package
+type zipEntry struct {
+	Source string // path on disk
+	Name   string // path inside the zip
+}
+
+func (r IncludeRule) validate() error {
+	if r.Source == "" {
+		return fmt.Errorf("❌ include rules require a Source glob")
+	}
+	if _, err := path.Match(strings.ReplaceAll(r.Source, "**", "*"), ""); err != nil {
+		return fmt.Errorf("❌ invalid include glob '%s': %w", r.Source, err)
+	}
+	if strings.HasPrefix(r.Destination, "/") || strings.Contains(r.Destination, "..") {
+		return fmt.Errorf("❌ include destination '%s' must be a relative path inside the package", r.Destination)
+	}
+	return nil
+}
+
+// includedFiles resolves the include rules against the project directory.
+// The result is sorted by name so the package stays deterministic.
+func includedFiles(root string, rules []IncludeRule) ([]zipEntry, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	seen := map[string]string{}
+	matched := make([]bool, len(rules))
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			// Never package build output, VCS data or GoZap state
+			if rel == "bin" || rel == ".git" || rel == ".gozap" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		for i, rule := range rules {
+			if !matchGlob(rule.Source, rel) {
+				continue
+			}
+			matched[i] = true
+			name := path.Join(rule.Destination, rel)
+			if name == "bootstrap" || name == collectorConfigName {
+				return fmt.Errorf("❌ included file '%s' would overwrite the generated '%s'", rel, name)
+			}
+			if other, exists := seen[name]; exists && other != p {
+				return fmt.Errorf("❌ '%s' and '%s' are both packaged as '%s'", other, p, name)
+			}
+			seen[name] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]zipEntry, 0, len(seen))
+	for name, source := range seen {
+		entries = append(entries, zipEntry{Source: source, Name: name})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	for i, rule := range rules {
+		if !matched[i] {
+			fmt.Printf("Warning: include pattern '%s' matched no files\n", rule.Source)
+		}
+	}
+
+	return entries, nil
+}
+
+// matchGlob matches a slash separated path against a glob where "**" matches
+// zero or more path segments
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
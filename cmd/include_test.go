@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateStageInclude(t *testing.T) {
+	layer := "arn:aws:lambda:us-east-1:123456789012:layer:x:1"
+	runStageCases(t, []stageCase{
+		{name: "include and layers", change: func(c *DeploymentConfig) {
+			c.Include = []IncludeRule{{Source: "templates/**"}, {Source: "migrations/*.sql", Destination: "db"}}
+			c.Layers = []string{layer}
+		}},
+		{name: "include without source", change: func(c *DeploymentConfig) { c.Include = []IncludeRule{{}} }, wantErr: "Source"},
+		{name: "include outside the package", change: func(c *DeploymentConfig) {
+			c.Include = []IncludeRule{{Source: "static/**", Destination: "../static"}}
+		}, wantErr: "relative path"},
+		{name: "too many layers", change: func(c *DeploymentConfig) {
+			for range 6 {
+				c.Layers = append(c.Layers, layer)
+			}
+		}, wantErr: "at most 5 layers"},
+		{name: "layer arn", change: func(c *DeploymentConfig) { c.Layers = []string{"x"} }, wantErr: "layer version ARN"},
+	})
+}
+
+func TestIncludedFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"templates/index.html", "templates/partials/nav.html", "migrations/001.sql", "bin/bootstrap", "bootstrap", "index.html"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		rules   []IncludeRule
+		want    []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name:  "globs and destinations",
+			rules: []IncludeRule{{Source: "templates/**"}, {Source: "migrations/*.sql", Destination: "db"}},
+			want:  []string{"db/migrations/001.sql", "templates/index.html", "templates/partials/nav.html"},
+		},
+		{name: "build output is skipped", rules: []IncludeRule{{Source: "bin/**"}}},
+		{name: "generated file", rules: []IncludeRule{{Source: "bootstrap"}}, wantErr: true},
+		{
+			name:    "two files under one name",
+			rules:   []IncludeRule{{Source: "templates/index.html"}, {Source: "index.html", Destination: "templates"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := includedFiles(root, tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("includedFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("includedFiles() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayersTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:extensions:3"}
+	template := renderTemplate(t, stageConfig)
+
+	var lambda struct {
+		Layers []string `yaml:"Layers"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if !reflect.DeepEqual(lambda.Layers, stageConfig.Layers) {
+		t.Errorf("Lambda Layers = %v, want %v", lambda.Layers, stageConfig.Layers)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "config.yaml", name: "config.yaml", want: true},
+		{pattern: "config.yaml", name: "conf/config.yaml", want: false},
+		{pattern: "*.yaml", name: "config.yaml", want: true},
+		{pattern: "*.yaml", name: "conf/config.yaml", want: false},
+		{pattern: "templates/*", name: "templates/index.html", want: true},
+		{pattern: "templates/*", name: "templates/partials/nav.html", want: false},
+		{pattern: "templates/**", name: "templates/index.html", want: true},
+		{pattern: "templates/**", name: "templates/partials/nav.html", want: true},
+		{pattern: "templates/**", name: "templates", want: true},
+		{pattern: "templates/**", name: "static/index.html", want: false},
+		{pattern: "**/*.sql", name: "schema.sql", want: true},
+		{pattern: "**/*.sql", name: "db/migrations/001.sql", want: true},
+		{pattern: "**/*.sql", name: "db/migrations/001.go", want: false},
+		{pattern: "db/**/*.sql", name: "db/001.sql", want: true},
+		{pattern: "db/**/*.sql", name: "db/migrations/2024/001.sql", want: true},
+		{pattern: "db/**/*.sql", name: "other/db/001.sql", want: false},
+		{pattern: "**", name: "any/path/at/all", want: true},
+		{pattern: "a/**/b/**/c", name: "a/b/c", want: true},
+		{pattern: "a/**/b/**/c", name: "a/x/b/y/z/c", want: true},
+		{pattern: "a/**/b/**/c", name: "a/x/y/c", want: false},
+		{pattern: "static/?.css", name: "static/a.css", want: true},
+		{pattern: "static/[ab].css", name: "static/c.css", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeRuleUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want IncludeRule
+	}{
+		{json: `"templates/**"`, want: IncludeRule{Source: "templates/**"}},
+		{json: `{"Source": "static/*.css", "Destination": "public"}`, want: IncludeRule{Source: "static/*.css", Destination: "public"}},
+	}
+
+	for _, tt := range tests {
+		var got IncludeRule
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", tt.json, err)
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.json, got, tt.want)
+		}
+	}
+}
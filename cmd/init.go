@@ -20,6 +20,22 @@ func NewInitCommand() *cobra.Command {
 				opts.ProjectName = args[0]
 			}
 
+			// Prompt for missing values when running in a terminal
+			missing := opts.ProjectName == "" || opts.Stage == "" || opts.S3Bucket == ""
+			if missing && !opts.NonInteractive && isInteractive() {
+				if err := runInitWizard(cmd, opts); err != nil {
+					return err
+				}
+			}
+
+			// Fall back to the bootstrapped deployment bucket of the region
+			if opts.S3Bucket == "" {
+				if bucket := bootstrapBucket(defaultRegion()); bucket != "" {
+					fmt.Printf("Using bootstrapped deployment bucket '%s'\n", bucket)
+					opts.S3Bucket = bucket
+				}
+			}
+
 			// Validate required fields
 			if opts.ProjectName == "" {
 				return fmt.Errorf("❌ project name is required")
@@ -28,7 +44,13 @@ func NewInitCommand() *cobra.Command {
 				return fmt.Errorf("❌ stage is required")
 			}
 			if opts.S3Bucket == "" {
-				return fmt.Errorf("❌ S3 bucket is required")
+				return fmt.Errorf("❌ S3 bucket is required, pass --bucket or run 'gozap bootstrap' first")
+			}
+			if err := validateStageConfig(DeploymentConfig{Memory: opts.Memory, Timeout: opts.Timeout}); err != nil {
+				return err
+			}
+			if _, ok := frameworkAdapters[opts.Framework]; !ok {
+				return fmt.Errorf("❌ unsupported framework '%s', choose one of: gin, echo, chi, fiber, nethttp", opts.Framework)
 			}
 
 			return runInit(opts)
@@ -40,10 +62,9 @@ func NewInitCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&opts.S3Bucket, "bucket", "b", "", "S3 bucket for deployment artifacts")
 	cmd.Flags().IntVarP(&opts.Timeout, "timeout", "t", 30, "Lambda function timeout in seconds")
 	cmd.Flags().IntVarP(&opts.Memory, "memory", "m", 128, "Lambda function memory in MB")
-
-	cmd.MarkFlagRequired("name")
-	cmd.MarkFlagRequired("stage")
-	cmd.MarkFlagRequired("bucket")
+	cmd.Flags().BoolVar(&opts.Scaffold, "scaffold", false, "Generate a Lambda application (main.go, events.go, go.mod, Makefile)")
+	cmd.Flags().StringVar(&opts.Framework, "framework", "gin", "Web framework of the generated application (gin, echo, chi, fiber, nethttp)")
+	cmd.Flags().BoolVar(&opts.NonInteractive, "non-interactive", false, "Never prompt for missing values (for CI)")
 
 	return cmd
 }
@@ -51,8 +72,20 @@ func NewInitCommand() *cobra.Command {
 func runInit(opts *InitOptions) error {
 	fmt.Printf("🚀 Initializing GoZap project: %s (Stage: %s)\n", opts.ProjectName, opts.Stage)
 
-	// 1. Check if S3 bucket exists and is accessible
-	if err := checkS3Bucket(opts.S3Bucket); err != nil {
+	// The --region and --profile overrides become the stage's target
+	stageConfig := DeploymentConfig{
+		FunctionName: fmt.Sprintf("%s-%s", opts.ProjectName, opts.Stage),
+		S3Bucket:     opts.S3Bucket,
+		S3Key:        "", // Will be set during deployment
+		Timeout:      opts.Timeout,
+		Memory:       opts.Memory,
+		Stage:        opts.Stage,
+		Region:       targetOverrides.Region,
+		Profile:      targetOverrides.Profile,
+	}
+
+	// 1. Check if S3 bucket exists in the stage's region and is accessible
+	if err := checkStageBucket(stageConfig); err != nil {
 		return err
 	}
 
@@ -68,13 +101,15 @@ func runInit(opts *InitOptions) error {
 	}
 
 	// 4. Create new stage configuration with provided values
-	config[opts.Stage] = DeploymentConfig{
-		FunctionName: fmt.Sprintf("%s-%s", opts.ProjectName, opts.Stage),
-		S3Bucket:     opts.S3Bucket,
-		S3Key:        "", // Will be set during deployment
-		Timeout:      opts.Timeout,
-		Memory:       opts.Memory,
-		Stage:        opts.Stage,
+	config[opts.Stage] = stageConfig
+
+	// Let the user review the stage before saving it
+	if opts.Interactive {
+		previewStage(opts.Stage, config[opts.Stage])
+		if !confirmAction("Save this configuration?") {
+			fmt.Println("❌ Initialization cancelled")
+			return nil
+		}
 	}
 
 	// 5. Write config back to file
@@ -82,7 +117,14 @@ func runInit(opts *InitOptions) error {
 		return err
 	}
 
-	// 6. Display summary
+	// 6. Generate the application when requested
+	if opts.Scaffold {
+		if err := scaffoldProject(opts); err != nil {
+			return err
+		}
+	}
+
+	// 7. Display summary
 	fmt.Println("✅ Project initialized successfully!")
 	fmt.Println("📂 Configuration created:")
 	fmt.Printf("  - Function Name: %s\n", config[opts.Stage].FunctionName)
@@ -92,6 +134,9 @@ func runInit(opts *InitOptions) error {
 	fmt.Printf("  - Memory: %d MB\n", config[opts.Stage].Memory)
 	fmt.Println("📝 Config saved to: config.json")
 	fmt.Println()
+	if opts.Scaffold {
+		fmt.Println("💡 Run 'go mod tidy' and 'make run' to try the application locally")
+	}
 	fmt.Println("Next steps:")
 	fmt.Printf("  1. Run 'gozap deploy --stage %s' to deploy your Lambda function\n", opts.Stage)
 	fmt.Printf("  2. Run 'gozap update --stage %s' to update an existing deployment\n", opts.Stage)
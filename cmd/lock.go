@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	lockTTL       = 15 * time.Minute
+	lockHeartbeat = lockTTL / 3
+)
+
+// LockInfo is the content of the lock object stored in the deployment bucket
+type LockInfo struct {
+	ID         string
+	Owner      string
+	CallerArn  string `json:",omitempty"`
+	Command    string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// deployLock is a per-stage lock held through an S3 object that is created
+// with a conditional put and kept alive by a heartbeat until released
+type deployLock struct {
+	bucket string
+	key    string
+	info   LockInfo
+
+	mu   sync.Mutex
+	etag string
+	err  error // set once the lock is no longer known to be ours
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewLockCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Inspect or release the deploy lock of a stage",
+		Long:  `Inspect or release the per-stage deploy lock that prevents concurrent deployments to the same stage.`,
+	}
+
+	cmd.AddCommand(newLockStatusCommand())
+	cmd.AddCommand(newLockReleaseCommand())
+
+	return cmd
+}
+
+func newLockStatusCommand() *cobra.Command {
+	opts := &LockOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show who holds the deploy lock of a stage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLockStatus(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func newLockReleaseCommand() *cobra.Command {
+	opts := &LockOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "Release a stuck deploy lock of a stage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLockRelease(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation prompt")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func runLockStatus(opts *LockOptions) error {
+	stageConfig, stackName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+
+	// Every region of the stage has its own lock
+	for _, regional := range regionalConfigs(stageConfig) {
+		if err := useTarget(&regional); err != nil {
+			return err
+		}
+		info, _, err := readLock(regional.S3Bucket, lockKey(stackName))
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			fmt.Printf("🔓 Stage '%s' is not locked\n", opts.Stage)
+			continue
+		}
+		printLock(opts.Stage, info)
+	}
+	return nil
+}
+
+func runLockRelease(opts *LockOptions) error {
+	stageConfig, stackName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+
+	for _, regional := range regionalConfigs(stageConfig) {
+		if err := releaseLock(regional, stackName, opts.Force); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseLock deletes the lock of the stage in the stage's region, asking
+// for confirmation while it is active unless force is set
+func releaseLock(stageConfig DeploymentConfig, stackName string, force bool) error {
+	if err := useTarget(&stageConfig); err != nil {
+		return err
+	}
+
+	key := lockKey(stackName)
+	info, etag, err := readLock(stageConfig.S3Bucket, key)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		fmt.Printf("🔓 Stage '%s' is not locked\n", stageConfig.Stage)
+		return nil
+	}
+
+	printLock(stageConfig.Stage, info)
+
+	if !force && time.Now().Before(info.ExpiresAt) {
+		fmt.Printf("\n⚠️  WARNING: The lock is still active, releasing it may let a concurrent deployment run\n\n")
+		if !confirmAction("Are you sure you want to release the lock?") {
+			fmt.Println("❌ Lock release cancelled")
+			return nil
+		}
+	}
+
+	// Only delete the lock that was shown, not one acquired in the meantime
+	if err := deleteLockObject(stageConfig.S3Bucket, key, etag); err != nil {
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("❌ the lock of stage '%s' changed while confirming, run 'gozap lock status' again", stageConfig.Stage)
+		}
+		return err
+	}
+
+	fmt.Printf("✅ Released deploy lock of stage '%s'\n", stageConfig.Stage)
+	return nil
+}
+
+// loadStage reads a stage from config.json and returns it with its stack name
+func loadStage(stage string) (DeploymentConfig, string, error) {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return DeploymentConfig{}, "", err
+	}
+
+	stageConfig, exists := config[stage]
+	if !exists {
+		return DeploymentConfig{}, "", fmt.Errorf("❌ stage '%s' not found in configuration", stage)
+	}
+	stageConfig.Stage = stage
+
+	return stageConfig, fmt.Sprintf("%s-%s", stageConfig.FunctionName, stage), nil
+}
+
+// loadStageTarget loads a stage and points the aws CLI at its account and
+// region
+func loadStageTarget(stage string) (DeploymentConfig, string, error) {
+	stageConfig, stackName, err := loadStage(stage)
+	if err != nil {
+		return DeploymentConfig{}, "", err
+	}
+	if err := useTarget(&stageConfig); err != nil {
+		return DeploymentConfig{}, "", err
+	}
+	return stageConfig, stackName, nil
+}
+
+func printLock(stage string, info *LockInfo) {
+	state := "active"
+	if time.Now().After(info.ExpiresAt) {
+		state = "expired"
+	}
+
+	fmt.Printf("🔒 Stage '%s' is locked (%s)\n", stage, state)
+	fmt.Printf("  - Owner: %s\n", info.Owner)
+	if info.CallerArn != "" {
+		fmt.Printf("  - AWS Identity: %s\n", info.CallerArn)
+	}
+	fmt.Printf("  - Command: %s\n", info.Command)
+	fmt.Printf("  - Acquired: %s\n", info.AcquiredAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  - Expires: %s\n", info.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+func lockKey(stackName string) string {
+	return fmt.Sprintf("gozap-locks/%s.lock", stackName)
+}
+
+// acquireLock takes the deploy lock of a stack. An expired lock left behind
+// by a crashed run is taken over; an active one fails with its owner details.
+func acquireLock(bucket, stackName, command string) (*deployLock, error) {
+	fmt.Printf("Acquiring deploy lock for '%s'...\n", stackName)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate lock id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	lock := &deployLock{
+		bucket: bucket,
+		key:    lockKey(stackName),
+		info: LockInfo{
+			ID:         hex.EncodeToString(id),
+			Owner:      lockOwner(),
+			CallerArn:  callerArn(),
+			Command:    command,
+			AcquiredAt: now,
+			ExpiresAt:  now.Add(lockTTL),
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	etag, err := putLockObject(bucket, lock.key, lock.info, "--if-none-match", "*")
+	if err != nil && isPreconditionFailed(err) {
+		existing, existingEtag, readErr := readLock(bucket, lock.key)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if existing != nil && time.Now().Before(existing.ExpiresAt) {
+			return nil, fmt.Errorf("❌ stage is locked by %s (%s) since %s, expires %s. Use 'gozap lock release' if it is stuck",
+				existing.Owner, existing.Command,
+				existing.AcquiredAt.Local().Format("15:04:05"),
+				existing.ExpiresAt.Local().Format("15:04:05"))
+		}
+
+		// Take over an expired lock (or one released since our first attempt)
+		fmt.Println("Taking over expired deploy lock...")
+		if existing == nil {
+			etag, err = putLockObject(bucket, lock.key, lock.info, "--if-none-match", "*")
+		} else {
+			etag, err = putLockObject(bucket, lock.key, lock.info, "--if-match", existingEtag)
+		}
+		if err != nil && isPreconditionFailed(err) {
+			return nil, fmt.Errorf("❌ another deployment acquired the lock first, try again later")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire deploy lock: %w", err)
+	}
+
+	lock.etag = etag
+	go lock.heartbeat()
+
+	return lock, nil
+}
+
+// heartbeat extends the lock expiry until the lock is released. A failed
+// refresh is retried on the next tick; the lock counts as lost once another
+// run overwrote it or it expired without a successful refresh.
+func (l *deployLock) heartbeat() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(lockHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			info := l.info
+			info.ExpiresAt = time.Now().UTC().Add(lockTTL)
+			etag, err := putLockObject(l.bucket, l.key, info, "--if-match", l.etag)
+			switch {
+			case err == nil:
+				l.etag, l.info, l.err = etag, info, nil
+			case isPreconditionFailed(err):
+				l.err = fmt.Errorf("❌ the deploy lock of '%s' was taken over by another run", l.key)
+			case time.Now().After(l.info.ExpiresAt):
+				l.err = fmt.Errorf("❌ the deploy lock of '%s' expired, refreshing it failed: %w", l.key, err)
+			}
+			lost := l.err != nil
+			l.mu.Unlock()
+
+			if lost {
+				return
+			}
+			if err != nil {
+				fmt.Printf("Warning: failed to refresh deploy lock, retrying: %v\n", err)
+			}
+		}
+	}
+}
+
+// Check returns an error once the lock is lost. Commands call it before each
+// change to the stack so they stop instead of racing another run.
+func (l *deployLock) Check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Release stops the heartbeat and deletes the lock if it is still ours. The
+// delete is conditional on the lock's ETag, so a lock taken over by another
+// run is left alone.
+func (l *deployLock) Release() {
+	close(l.stop)
+	<-l.done
+
+	if err := l.Check(); err != nil {
+		fmt.Printf("⚠️  %v, another run may have changed the stage concurrently\n", strings.TrimPrefix(err.Error(), "❌ "))
+		return
+	}
+
+	if err := deleteLockObject(l.bucket, l.key, l.etag); err != nil && !isPreconditionFailed(err) {
+		fmt.Printf("Warning: failed to release deploy lock: %v\n", err)
+	}
+}
+
+func lockOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s (pid %d)", name, host, os.Getpid())
+}
+
+// putLockObject writes the lock with the given S3 precondition and returns
+// the ETag of the new object
+func putLockObject(bucket, key string, info LockInfo, condition, value string) (string, error) {
+	content, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := os.CreateTemp("", "gozap-lock-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(body.Name())
+
+	if _, err := body.Write(content); err != nil {
+		body.Close()
+		return "", err
+	}
+	body.Close()
+
+	cmd := awsCommand(
+		"s3api", "put-object",
+		"--bucket", bucket,
+		"--key", key,
+		"--body", body.Name(),
+		"--content-type", "application/json",
+		condition, value,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w\n%s", err, output)
+	}
+
+	var response struct {
+		ETag string `json:"ETag"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", fmt.Errorf("failed to parse put-object response: %w", err)
+	}
+	return response.ETag, nil
+}
+
+// readLock returns the current lock and its ETag, or nil if there is none
+func readLock(bucket, key string) (*LockInfo, string, error) {
+	body, err := os.CreateTemp("", "gozap-lock-*.json")
+	if err != nil {
+		return nil, "", err
+	}
+	body.Close()
+	defer os.Remove(body.Name())
+
+	cmd := awsCommand("s3api", "get-object", "--bucket", bucket, "--key", key, body.Name())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "NoSuchKey") {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read deploy lock: %w\n%s", err, output)
+	}
+
+	var response struct {
+		ETag string `json:"ETag"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to parse get-object response: %w", err)
+	}
+
+	content, err := os.ReadFile(body.Name())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil, "", fmt.Errorf("failed to parse deploy lock: %w", err)
+	}
+	return &info, response.ETag, nil
+}
+
+// deleteLockObject deletes the lock, only if it still has the given ETag
+// unless etag is empty
+func deleteLockObject(bucket, key, etag string) error {
+	args := []string{"s3api", "delete-object", "--bucket", bucket, "--key", key}
+	if etag != "" {
+		args = append(args, "--if-match", etag)
+	}
+	cmd := awsCommand(args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete deploy lock: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// isPreconditionFailed reports whether a conditional S3 write lost the race
+func isPreconditionFailed(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "PreconditionFailed") || strings.Contains(msg, "ConditionalRequestConflict")
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeS3Script stands in for the aws CLI. It keeps objects as files under
+// $GOZAP_FAKE_S3 and honours the conditional writes and deletes locks use.
+const fakeS3Script = `#!/bin/sh
+[ "$1" = s3api ] || exit 1
+op=$2
+shift 2
+bucket= key= body= cond= value= out=
+while [ $# -gt 0 ]; do
+  case $1 in
+    --bucket) bucket=$2; shift 2 ;;
+    --key) key=$2; shift 2 ;;
+    --body) body=$2; shift 2 ;;
+    --content-type) shift 2 ;;
+    --if-none-match|--if-match) cond=$1; value=$2; shift 2 ;;
+    *) out=$1; shift ;;
+  esac
+done
+obj="$GOZAP_FAKE_S3/$bucket/$key"
+etag() { md5sum < "$obj" | cut -d' ' -f1; }
+failed() {
+  case $cond in
+    --if-none-match) [ -e "$obj" ] ;;
+    --if-match) [ ! -e "$obj" ] || [ "$(etag)" != "$value" ] ;;
+    *) false ;;
+  esac
+}
+case $op in
+  put-object)
+    if failed; then echo "An error occurred (PreconditionFailed)" >&2; exit 254; fi
+    mkdir -p "$(dirname "$obj")" && cp "$body" "$obj"
+    echo "{\"ETag\": \"$(etag)\"}" ;;
+  get-object)
+    if [ ! -e "$obj" ]; then echo "An error occurred (NoSuchKey)" >&2; exit 254; fi
+    cp "$obj" "$out"
+    echo "{\"ETag\": \"$(etag)\"}" ;;
+  delete-object)
+    if failed; then echo "An error occurred (PreconditionFailed)" >&2; exit 254; fi
+    rm -f "$obj" ;;
+  *) exit 1 ;;
+esac
+`
+
+// fakeS3 puts the fake aws CLI first on the PATH
+func fakeS3(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake aws CLI is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "aws"), []byte(fakeS3Script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GOZAP_FAKE_S3", t.TempDir())
+}
+
+func TestAcquireLock(t *testing.T) {
+	fakeS3(t)
+
+	lock, err := acquireLock("bucket", "app-dev", "deploy")
+	if err != nil {
+		t.Fatalf("acquireLock() error = %v", err)
+	}
+	if err := lock.Check(); err != nil {
+		t.Errorf("Check() = %v on a fresh lock", err)
+	}
+
+	if _, err := acquireLock("bucket", "app-dev", "update"); err == nil || !strings.Contains(err.Error(), "locked by") {
+		t.Errorf("acquireLock() on an active lock = %v, want a locked error", err)
+	}
+	if other, err := acquireLock("bucket", "app-prod", "update"); err != nil {
+		t.Errorf("acquireLock() on another stack = %v", err)
+	} else {
+		other.Release()
+	}
+
+	lock.Release()
+	if info, _, err := readLock("bucket", lockKey("app-dev")); err != nil || info != nil {
+		t.Fatalf("lock after Release() = %+v, %v, want none", info, err)
+	}
+
+	again, err := acquireLock("bucket", "app-dev", "update")
+	if err != nil {
+		t.Fatalf("acquireLock() after Release() error = %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireExpiredLock(t *testing.T) {
+	fakeS3(t)
+
+	past := time.Now().UTC().Add(-time.Hour)
+	stale := LockInfo{ID: "crashed", Owner: "ci", Command: "deploy", AcquiredAt: past.Add(-lockTTL), ExpiresAt: past}
+	if _, err := putLockObject("bucket", lockKey("app-dev"), stale, "--if-none-match", "*"); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireLock("bucket", "app-dev", "deploy")
+	if err != nil {
+		t.Fatalf("acquireLock() on an expired lock error = %v", err)
+	}
+	defer lock.Release()
+
+	info, _, err := readLock("bucket", lockKey("app-dev"))
+	if err != nil || info == nil || info.ID != lock.info.ID {
+		t.Errorf("lock after the takeover = %+v, %v, want ours (%s)", info, err, lock.info.ID)
+	}
+}
+
+func TestReleaseTakenOverLock(t *testing.T) {
+	fakeS3(t)
+
+	lock, err := acquireLock("bucket", "app-dev", "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another run takes the lock over, e.g. after 'lock release'
+	other := LockInfo{ID: "other", Owner: "ci", Command: "update", AcquiredAt: time.Now().UTC(), ExpiresAt: time.Now().UTC().Add(lockTTL)}
+	if _, err := putLockObject("bucket", lockKey("app-dev"), other, "--if-match", lock.etag); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Release()
+	info, _, err := readLock("bucket", lockKey("app-dev"))
+	if err != nil || info == nil || info.ID != "other" {
+		t.Errorf("lock after Release() = %+v, %v, want the other run's lock left in place", info, err)
+	}
+}
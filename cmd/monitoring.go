@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// apiGatewayTimeout is the maximum integration timeout of REST APIs in seconds
+const apiGatewayTimeout = 29
+
+// MonitoringConfig generates CloudWatch alarms and a dashboard for the stage.
+// Alarms notify a topic created for Emails or the existing TopicArn. Unset
+// thresholds default to values derived from the stage's Timeout. None is
+// derived from Memory: Lambda publishes no memory usage metric without the
+// Lambda Insights extension, and since CPU scales with memory an undersized
+// function already trips the duration alarm. The dashboard shows the memory
+// size next to the durations instead.
+type MonitoringConfig struct {
+	Emails          []string `json:",omitempty"`
+	TopicArn        string   `json:",omitempty"`
+	Errors          int      `json:",omitempty"` // Lambda errors per 5 minutes, default 1
+	Throttles       int      `json:",omitempty"` // Lambda throttles per 5 minutes, default 1
+	DurationPercent int      `json:",omitempty"` // p99 duration as a percentage of Timeout, default 80
+	Api5xxPercent   float64  `json:",omitempty"` // share of API requests failing with 5XX, default 1
+	ApiLatency      int      `json:",omitempty"` // p99 API latency in ms, default 80% of the integration timeout
+}
+
+func (m *MonitoringConfig) validate() error {
+	if len(m.Emails) == 0 && m.TopicArn == "" {
+		return fmt.Errorf("❌ Monitoring requires Emails or the TopicArn to notify")
+	}
+	if len(m.Emails) > 0 && m.TopicArn != "" {
+		return fmt.Errorf("❌ Monitoring takes either Emails or TopicArn, subscribe the emails to the existing topic instead")
+	}
+	if m.TopicArn != "" && !strings.HasPrefix(m.TopicArn, "arn:aws:sns:") {
+		return fmt.Errorf("❌ '%s' is not an SNS topic ARN", m.TopicArn)
+	}
+	for _, email := range m.Emails {
+		if !strings.Contains(email, "@") || strings.ContainsAny(email, " '\"") {
+			return fmt.Errorf("❌ '%s' is not a valid email address", email)
+		}
+	}
+	if m.Errors < 0 || m.Throttles < 0 || m.ApiLatency < 0 {
+		return fmt.Errorf("❌ Monitoring thresholds cannot be negative")
+	}
+	if m.DurationPercent < 0 || m.DurationPercent > 100 {
+		return fmt.Errorf("❌ Monitoring DurationPercent must be between 1 and 100, or omitted for the default of 80, got %d", m.DurationPercent)
+	}
+	if m.Api5xxPercent < 0 || m.Api5xxPercent > 100 {
+		return fmt.Errorf("❌ Monitoring Api5xxPercent must be above 0 and at most 100, or omitted for the default of 1, got %g", m.Api5xxPercent)
+	}
+	return nil
+}
+
+// AlarmAction returns the topic alarms notify
+func (m *MonitoringConfig) AlarmAction() string {
+	if m.TopicArn != "" {
+		return m.TopicArn
+	}
+	return "!Ref AlarmTopic"
+}
+
+// alarm is a CloudWatch alarm on a single metric
+type alarm struct {
+	Name              string
+	Description       string
+	Namespace         string
+	MetricName        string
+	Dimensions        map[string]string
+	Statistic         string
+	ExtendedStatistic string
+	Threshold         string
+}
+
+// Alarms returns the alarms of the stage with the thresholds resolved
+func (c DeploymentConfig) Alarms() []alarm {
+	m := c.Monitoring
+	if m == nil {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s", c.FunctionName, c.Stage)
+	function := map[string]string{"FunctionName": name}
+	api := map[string]string{"ApiName": name, "Stage": c.Stage}
+
+	errors := orDefault(m.Errors, 1)
+	throttles := orDefault(m.Throttles, 1)
+	durationPercent := orDefault(m.DurationPercent, 80)
+	duration := c.Timeout * 1000 * durationPercent / 100
+	latency := orDefault(m.ApiLatency, min(c.Timeout, apiGatewayTimeout)*1000*80/100)
+	api5xx := m.Api5xxPercent
+	if api5xx == 0 {
+		api5xx = 1
+	}
+
+	return []alarm{
+		{
+			Name:        "ErrorsAlarm",
+			Description: fmt.Sprintf("%s: %d or more Lambda errors in 5 minutes", name, errors),
+			Namespace:   "AWS/Lambda", MetricName: "Errors", Dimensions: function,
+			Statistic: "Sum", Threshold: strconv.Itoa(errors),
+		},
+		{
+			Name:        "ThrottlesAlarm",
+			Description: fmt.Sprintf("%s: %d or more Lambda throttles in 5 minutes", name, throttles),
+			Namespace:   "AWS/Lambda", MetricName: "Throttles", Dimensions: function,
+			Statistic: "Sum", Threshold: strconv.Itoa(throttles),
+		},
+		{
+			Name:        "DurationAlarm",
+			Description: fmt.Sprintf("%s: p99 duration at %d%% or more of the %ds timeout", name, durationPercent, c.Timeout),
+			Namespace:   "AWS/Lambda", MetricName: "Duration", Dimensions: function,
+			ExtendedStatistic: "p99", Threshold: strconv.Itoa(duration),
+		},
+		{
+			Name:        "Api5xxAlarm",
+			Description: fmt.Sprintf("%s: %g%% or more of API requests fail with 5XX", name, api5xx),
+			Namespace:   "AWS/ApiGateway", MetricName: "5XXError", Dimensions: api,
+			Statistic: "Average", Threshold: strconv.FormatFloat(api5xx/100, 'f', -1, 64),
+		},
+		{
+			Name:        "ApiLatencyAlarm",
+			Description: fmt.Sprintf("%s: p99 API latency of %dms or more", name, latency),
+			Namespace:   "AWS/ApiGateway", MetricName: "Latency", Dimensions: api,
+			ExtendedStatistic: "p99", Threshold: strconv.Itoa(latency),
+		},
+	}
+}
+
+func orDefault(value, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
+
+// DashboardBody returns the dashboard definition for Fn::Sub, quoted as a
+// single-quoted YAML string
+func (c DeploymentConfig) DashboardBody() string {
+	name := fmt.Sprintf("%s-%s", c.FunctionName, c.Stage)
+	widget := func(x, y int, title string, metrics [][]any, annotations map[string]any) map[string]any {
+		properties := map[string]any{
+			"title":   title,
+			"region":  "${AWS::Region}",
+			"metrics": metrics,
+			"period":  300,
+			"view":    "timeSeries",
+		}
+		if annotations != nil {
+			properties["annotations"] = annotations
+		}
+		return map[string]any{"type": "metric", "x": x, "y": y, "width": 12, "height": 6, "properties": properties}
+	}
+	lambda := func(metric, stat string) []any {
+		return []any{"AWS/Lambda", metric, "FunctionName", name, map[string]string{"stat": stat}}
+	}
+	api := func(metric, stat string) []any {
+		return []any{"AWS/ApiGateway", metric, "ApiName", name, "Stage", c.Stage, map[string]string{"stat": stat}}
+	}
+
+	timeout := map[string]any{"horizontal": []map[string]any{{"label": "Timeout", "value": c.Timeout * 1000}}}
+	body := map[string]any{
+		"widgets": []map[string]any{
+			widget(0, 0, "Invocations, errors and throttles",
+				[][]any{lambda("Invocations", "Sum"), lambda("Errors", "Sum"), lambda("Throttles", "Sum")}, nil),
+			widget(12, 0, fmt.Sprintf("Duration (ms, %d MB memory)", c.Memory),
+				[][]any{lambda("Duration", "p50"), lambda("Duration", "p99"), lambda("Duration", "Maximum")}, timeout),
+			widget(0, 6, "API requests and errors",
+				[][]any{api("Count", "Sum"), api("4XXError", "Sum"), api("5XXError", "Sum")}, nil),
+			widget(12, 6, "API latency (ms)",
+				[][]any{api("Latency", "p50"), api("Latency", "p99"), api("IntegrationLatency", "p99")}, nil),
+			widget(0, 12, "Concurrent executions",
+				[][]any{lambda("ConcurrentExecutions", "Maximum")}, nil),
+		},
+	}
+
+	content, _ := json.Marshal(body)
+	return "'" + strings.ReplaceAll(string(content), "'", "''") + "'"
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMonitoringConfigValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		monitoring MonitoringConfig
+		wantErr    bool
+	}{
+		{name: "emails", monitoring: MonitoringConfig{Emails: []string{"oncall@example.com"}}},
+		{name: "topic", monitoring: MonitoringConfig{TopicArn: "arn:aws:sns:us-east-1:123456789012:alarms"}},
+		{name: "emails and topic", monitoring: MonitoringConfig{Emails: []string{"a@example.com"}, TopicArn: "arn:aws:sns:us-east-1:123456789012:alarms"}, wantErr: true},
+		{name: "topic arn", monitoring: MonitoringConfig{TopicArn: "alarms"}, wantErr: true},
+		{name: "email", monitoring: MonitoringConfig{Emails: []string{"oncall"}}, wantErr: true},
+		{name: "negative errors", monitoring: MonitoringConfig{Emails: []string{"a@example.com"}, Errors: -1}, wantErr: true},
+		{name: "duration percent", monitoring: MonitoringConfig{Emails: []string{"a@example.com"}, DurationPercent: 100}},
+		{name: "duration percent too high", monitoring: MonitoringConfig{Emails: []string{"a@example.com"}, DurationPercent: 101}, wantErr: true},
+		{name: "5xx percent too high", monitoring: MonitoringConfig{Emails: []string{"a@example.com"}, Api5xxPercent: 100.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.monitoring.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageMonitoring(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "monitoring", change: func(c *DeploymentConfig) { c.Monitoring = &MonitoringConfig{Emails: []string{"oncall@example.com"}} }},
+		{name: "monitoring without recipients", change: func(c *DeploymentConfig) { c.Monitoring = &MonitoringConfig{} }, wantErr: "Emails or the TopicArn"},
+	})
+}
+
+func TestAlarms(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    int
+		monitoring MonitoringConfig
+		want       map[string]string // thresholds by alarm name
+	}{
+		{
+			name:    "defaults",
+			timeout: 30,
+			want: map[string]string{
+				"ErrorsAlarm": "1", "ThrottlesAlarm": "1", "DurationAlarm": "24000",
+				"Api5xxAlarm": "0.01", "ApiLatencyAlarm": "23200",
+			},
+		},
+		{
+			name:       "thresholds",
+			timeout:    10,
+			monitoring: MonitoringConfig{Errors: 5, Throttles: 2, DurationPercent: 50, Api5xxPercent: 2.5, ApiLatency: 1500},
+			want: map[string]string{
+				"ErrorsAlarm": "5", "ThrottlesAlarm": "2", "DurationAlarm": "5000",
+				"Api5xxAlarm": "0.025", "ApiLatencyAlarm": "1500",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageConfig := validStage()
+			stageConfig.Timeout = tt.timeout
+			stageConfig.Monitoring = &tt.monitoring
+			alarms := stageConfig.Alarms()
+			if len(alarms) != len(tt.want) {
+				t.Fatalf("Alarms() = %d alarms, want %d", len(alarms), len(tt.want))
+			}
+			for _, alarm := range alarms {
+				if alarm.Threshold != tt.want[alarm.Name] {
+					t.Errorf("%s threshold = %s, want %s", alarm.Name, alarm.Threshold, tt.want[alarm.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestMonitoringTemplate(t *testing.T) {
+	type alarm struct {
+		AlarmActions []string `yaml:"AlarmActions"`
+		MetricName   string   `yaml:"MetricName"`
+		Threshold    string   `yaml:"Threshold"`
+	}
+
+	t.Run("emails", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Monitoring = &MonitoringConfig{Emails: []string{"oncall@example.com"}}
+		template := renderTemplate(t, stageConfig)
+
+		var topic struct {
+			Subscription []struct {
+				Endpoint string `yaml:"Endpoint"`
+				Protocol string `yaml:"Protocol"`
+			} `yaml:"Subscription"`
+		}
+		template.resource(t, "AlarmTopic", "AWS::SNS::Topic", &topic)
+		if len(topic.Subscription) != 1 || topic.Subscription[0].Endpoint != "oncall@example.com" || topic.Subscription[0].Protocol != "email" {
+			t.Errorf("AlarmTopic Subscription = %+v", topic.Subscription)
+		}
+
+		for _, want := range stageConfig.Alarms() {
+			var got alarm
+			template.resource(t, want.Name, "AWS::CloudWatch::Alarm", &got)
+			if got.MetricName != want.MetricName || got.Threshold != want.Threshold || len(got.AlarmActions) != 1 || got.AlarmActions[0] != "AlarmTopic" {
+				t.Errorf("%s = %+v, want %s at %s notifying AlarmTopic", want.Name, got, want.MetricName, want.Threshold)
+			}
+		}
+
+		var dashboard struct {
+			DashboardBody string `yaml:"DashboardBody"`
+		}
+		template.resource(t, "Dashboard", "AWS::CloudWatch::Dashboard", &dashboard)
+		var body struct {
+			Widgets []map[string]any `json:"widgets"`
+		}
+		if err := json.Unmarshal([]byte(dashboard.DashboardBody), &body); err != nil {
+			t.Fatalf("DashboardBody is not JSON: %v\n%s", err, dashboard.DashboardBody)
+		}
+		if len(body.Widgets) != 5 {
+			t.Errorf("dashboard has %d widgets, want 5", len(body.Widgets))
+		}
+	})
+
+	t.Run("topic", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Monitoring = &MonitoringConfig{TopicArn: "arn:aws:sns:us-east-1:123456789012:alarms"}
+		template := renderTemplate(t, stageConfig)
+
+		if _, ok := template.Resources["AlarmTopic"]; ok {
+			t.Error("a topic is created although the stage notifies an existing one")
+		}
+		var got alarm
+		template.resource(t, "ErrorsAlarm", "AWS::CloudWatch::Alarm", &got)
+		if len(got.AlarmActions) != 1 || got.AlarmActions[0] != stageConfig.Monitoring.TopicArn {
+			t.Errorf("ErrorsAlarm AlarmActions = %v, want the existing topic", got.AlarmActions)
+		}
+	})
+}
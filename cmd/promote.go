@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPromoteCommand() *cobra.Command {
+	opts := &PromoteOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Finish a traffic shift or promote a build to another stage",
+		Long: `Finish an in-progress canary or linear traffic shift by sending all traffic of the stage alias to the new Lambda version (--stage),
+or deploy the exact artifact running in one stage to another stage without rebuilding (--from/--to).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.From != "" {
+				return runPromoteArtifact(opts)
+			}
+			return runPromote(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage whose traffic shift should be completed")
+	cmd.Flags().StringVar(&opts.From, "from", "", "Stage to take the deployed artifact from (e.g., staging)")
+	cmd.Flags().StringVar(&opts.To, "to", "", "Stage to deploy the artifact to (e.g., prod)")
+	cmd.MarkFlagsRequiredTogether("from", "to")
+	cmd.MarkFlagsOneRequired("stage", "from")
+	cmd.MarkFlagsMutuallyExclusive("stage", "from")
+
+	return cmd
+}
+
+func runPromote(opts *PromoteOptions) error {
+	fmt.Printf("⏩ Promoting new version for stage: %s\n", opts.Stage)
+
+	// 1. Read config file
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	// 2. Validate stage exists in config
+	stageConfig, exists := config[opts.Stage]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.Stage)
+	}
+
+	// Point the aws CLI at the stage's account and region
+	if err := useTarget(&stageConfig); err != nil {
+		return err
+	}
+
+	functionName := fmt.Sprintf("%s-%s", stageConfig.FunctionName, opts.Stage)
+	// Acquire the deploy lock for the stage
+	lock, err := acquireLock(stageConfig.S3Bucket, functionName, "promote")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// 3. Shift all traffic of the stage alias to the new version
+	if err := promoteAlias(functionName, opts.Stage); err != nil {
+		return err
+	}
+
+	stageConfig.Stage = opts.Stage
+	recordHistory("promote", stageConfig, functionName)
+
+	fmt.Println("✅ All traffic is now served by the new version")
+	return nil
+}
+
+func runPromoteArtifact(opts *PromoteOptions) error {
+	fmt.Printf("⏩ Promoting artifact from stage '%s' to stage '%s'\n", opts.From, opts.To)
+
+	if opts.From == opts.To {
+		return fmt.Errorf("❌ source and target stage must differ")
+	}
+
+	// 1. Read config file
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	// 2. Validate both stages exist in config
+	fromConfig, exists := config[opts.From]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.From)
+	}
+	toConfig, exists := config[opts.To]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.To)
+	}
+
+	fromConfig.Stage = opts.From
+	toConfig.Stage = opts.To
+	if fromConfig.IsImage() != toConfig.IsImage() {
+		return fmt.Errorf("❌ stages '%s' and '%s' must use the same Package type", opts.From, opts.To)
+	}
+
+	// 3. Look up the artifact the source stage's alias serves, in its
+	// account and region. During a traffic shift that is the version most of
+	// the traffic still goes to, not the newest one.
+	if err := useTarget(&fromConfig); err != nil {
+		return err
+	}
+	fromTarget := currentTarget
+	fromStack := fmt.Sprintf("%s-%s", fromConfig.FunctionName, opts.From)
+	alias, err := getAlias(fromStack, opts.From)
+	if err != nil {
+		return err
+	}
+	if pending, weight := alias.pendingVersion(); pending != "" {
+		fmt.Printf("⚠️  Stage '%s' routes %g%% of traffic to version %s, promoting version %s that serves the rest\n", opts.From, weight*100, pending, alias.FunctionVersion)
+	}
+	artifact, err := versionArtifact(fromStack, alias.FunctionVersion)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Artifact deployed to '%s': %s\n", opts.From, artifact.Key())
+
+	// The Lambda authorizer is promoted along with the function
+	var authorizer *Artifact
+	if toConfig.Auth != nil && toConfig.Auth.Type == "lambda" {
+		authorizer, err = deployedArtifact(fromStack + "-authorizer")
+		if err != nil {
+			return fmt.Errorf("❌ stage '%s' has no Lambda authorizer to promote: %w", opts.From, err)
+		}
+		authorizer.Prefix = authorizerPrefix
+	}
+
+	if toConfig.IsImage() {
+		// Stages of a project share their ECR repository
+		if imageRepositoryName(fromConfig) != imageRepositoryName(toConfig) {
+			return fmt.Errorf("❌ images can only be promoted between stages of the same project")
+		}
+		if !imageExists(imageRepositoryName(fromConfig), artifact.Sha256) {
+			return fmt.Errorf("❌ image '%s' is no longer in repository '%s'", artifact.Sha256, imageRepositoryName(fromConfig))
+		}
+	} else if !s3ObjectExists(fromConfig.S3Bucket, artifact.Key()) {
+		return fmt.Errorf("❌ artifact '%s' is no longer in bucket '%s'", artifact.Key(), fromConfig.S3Bucket)
+	}
+
+	// The rest happens in the target stage's account and region. Copies
+	// between accounts need the target to be allowed to read the source bucket.
+	if err := useTarget(&toConfig); err != nil {
+		return err
+	}
+	if toConfig.IsImage() && !sameTarget(fromTarget, currentTarget) {
+		return fmt.Errorf("❌ images can only be promoted between stages in the same account and region")
+	}
+
+	// 4. Check if the target CloudFormation stack exists
+	toStack := fmt.Sprintf("%s-%s", toConfig.FunctionName, opts.To)
+	if err := checkStackExists(toStack); err != nil {
+		return fmt.Errorf("❌ stage '%s' has not been deployed yet, run 'gozap deploy --stage %s' first", opts.To, opts.To)
+	}
+
+	// Acquire the deploy lock for the stage
+	lock, err := acquireLock(toConfig.S3Bucket, toStack, "promote --from "+opts.From)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// 5. Copy the artifact to the target bucket if needed
+	if toConfig.IsImage() {
+		if err := resolveImageUri(&toConfig, artifact); err != nil {
+			return err
+		}
+	} else if toConfig.S3Bucket != fromConfig.S3Bucket {
+		if err := copyArtifact(artifact, fromConfig.S3Bucket, toConfig.S3Bucket); err != nil {
+			return err
+		}
+	}
+
+	toConfig.S3Key = artifact.Key()
+	toConfig.CodeSha256 = artifact.Sha256
+
+	if authorizer != nil {
+		if toConfig.S3Bucket != fromConfig.S3Bucket {
+			if err := copyArtifact(authorizer, fromConfig.S3Bucket, toConfig.S3Bucket); err != nil {
+				return err
+			}
+		}
+		toConfig.AuthorizerCodeSha256 = authorizer.Sha256
+	}
+
+	defer cleanupFiles([]string{"template.yaml"})
+
+	// 6. Generate CloudFormation template
+	if err := generateTemplate("template.yaml", &toConfig); err != nil {
+		return err
+	}
+	if err := checkEventBuckets(toStack, toConfig); err != nil {
+		return err
+	}
+
+	tags, err := stackTags(toConfig)
+	if err != nil {
+		return err
+	}
+
+	if isNoopDeploy(toStack, toStack, "template.yaml", artifact, tags) {
+		fmt.Printf("✅ Nothing to deploy: stage '%s' already runs this artifact\n", opts.To)
+		return nil
+	}
+
+	// 7. Update CloudFormation stack
+	if err := lock.Check(); err != nil {
+		return err
+	}
+	if err := updateStack(toStack, "template.yaml", toConfig.S3Bucket, tags, aliasRouting{}); err != nil {
+		return err
+	}
+
+	// 8. Wait for CloudFormation stack update to complete
+	if err := waitForStackUpdate(toStack); err != nil {
+		return err
+	}
+
+	// 9. Output the stack details
+	if err := outputStackDetails(toStack); err != nil {
+		return err
+	}
+
+	recordHistory("promote", toConfig, toStack)
+
+	fmt.Printf("✅ Stage '%s' now runs the artifact from stage '%s'\n", opts.To, opts.From)
+	return nil
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// prebuiltEnv passes the directory of the package built once by a
+// multi-region run to the process of each region
+const prebuiltEnv = "GOZAP_PREBUILT"
+
+// prebuiltManifest is written next to the package built once for a
+// multi-region deploy, so each region's process deploys the same artifacts
+type prebuiltManifest struct {
+	Stage        string
+	FunctionName string
+	Artifact     *Artifact
+	Authorizer   *Artifact `json:",omitempty"`
+}
+
+// regionResult is the outcome of deploying one region
+type regionResult struct {
+	Region   string
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// validateRegions checks the Regions and Buckets of a stage
+func validateRegions(stageConfig DeploymentConfig) error {
+	if stageConfig.Region != "" && len(stageConfig.Regions) > 0 {
+		return fmt.Errorf("❌ set either Region or Regions, not both")
+	}
+	seen := map[string]bool{}
+	for _, region := range stageConfig.Regions {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("❌ '%s' is not an AWS region", region)
+		}
+		if seen[region] {
+			return fmt.Errorf("❌ region '%s' is listed twice in Regions", region)
+		}
+		seen[region] = true
+	}
+	for region := range stageConfig.Buckets {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("❌ '%s' in Buckets is not an AWS region", region)
+		}
+	}
+	return nil
+}
+
+// fansOut reports whether a command on the stage must run once per region
+func fansOut(stageConfig DeploymentConfig) bool {
+	return len(stageConfig.Regions) > 1 && targetOverrides.Region == ""
+}
+
+// regionalConfigs returns the stage once per region a command acts on: every
+// region of a multi-region stage unless --region picks one, otherwise the
+// stage itself
+func regionalConfigs(stageConfig DeploymentConfig) []DeploymentConfig {
+	if !fansOut(stageConfig) {
+		return []DeploymentConfig{stageConfig}
+	}
+	configs := make([]DeploymentConfig, 0, len(stageConfig.Regions))
+	for _, region := range stageConfig.Regions {
+		regional := stageConfig
+		regional.Region = region
+		regional.Regions = nil
+		configs = append(configs, regional)
+	}
+	return configs
+}
+
+// prebuiltDir returns the directory of the package a multi-region run built
+// for this region's process, or "" when the command builds its own
+func prebuiltDir(stageConfig DeploymentConfig) (string, error) {
+	dir := os.Getenv(prebuiltEnv)
+	if dir == "" {
+		return "", nil
+	}
+	if targetOverrides.Region == "" || !slices.Contains(stageConfig.Regions, targetOverrides.Region) {
+		return "", fmt.Errorf("❌ %s is only set by multi-region runs for one of the stage's Regions, unset it", prebuiltEnv)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "prebuilt.json")); err != nil || info.IsDir() {
+		return "", fmt.Errorf("❌ %s points to '%s', which holds no prebuilt package", prebuiltEnv, dir)
+	}
+	return dir, nil
+}
+
+// packageStage builds the stage's function and authorizer packages, or loads
+// them from the directory a multi-region deploy built them in. A prebuilt
+// package must belong to the stage and still match its checksums.
+func packageStage(binDir string, stageConfig *DeploymentConfig, prebuilt string) (*Artifact, *Artifact, error) {
+	if prebuilt == "" {
+		artifact, err := packageArtifact(binDir, *stageConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		authorizer, err := prepareAuthorizer(binDir, stageConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return artifact, authorizer, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(prebuilt, "prebuilt.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read prebuilt package: %w", err)
+	}
+	var manifest prebuiltManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse prebuilt package: %w", err)
+	}
+
+	if manifest.Stage != stageConfig.Stage || manifest.FunctionName != stageConfig.FunctionName || manifest.Artifact == nil {
+		return nil, nil, fmt.Errorf("❌ prebuilt package in '%s' was not built for stage '%s'", prebuilt, stageConfig.Stage)
+	}
+	for _, artifact := range []*Artifact{manifest.Artifact, manifest.Authorizer} {
+		if artifact == nil {
+			continue
+		}
+		if hash, err := hashFile(artifact.Path); err != nil || hash != artifact.Sha256 {
+			return nil, nil, fmt.Errorf("❌ prebuilt package '%s' is missing or changed since it was built", artifact.Path)
+		}
+	}
+
+	fmt.Printf("Using prebuilt package %s\n", manifest.Artifact.Sha256)
+	if manifest.Authorizer != nil {
+		stageConfig.AuthorizerCodeSha256 = manifest.Authorizer.Sha256
+	}
+	return manifest.Artifact, manifest.Authorizer, nil
+}
+
+// runMultiRegion builds the stage once and runs the command for every region
+// of the stage in its own gozap process, at most opts.Parallel at a time.
+//
+// Regions run as processes rather than goroutines of this one: the deploy
+// path prints its progress to stdout and runs the aws CLI, docker and go
+// against the process-wide target and working files. A process per region
+// gives each region its own target and an output stream that can be prefixed
+// with the region, without threading both through every helper. The package
+// is still built once and handed over through GOZAP_PREBUILT.
+func runMultiRegion(command string, stageConfig DeploymentConfig, opts MultiRegionOptions, extraArgs []string) error {
+	regions := stageConfig.Regions
+	if opts.Parallel < 1 {
+		return fmt.Errorf("❌ --parallel must be at least 1")
+	}
+	fmt.Printf("🌍 Running %s of stage '%s' in %d regions\n", command, stageConfig.Stage, len(regions))
+
+	tempDir := "bin"
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	defer cleanupFiles([]string{tempDir})
+
+	// 1. Build once, every region deploys the same package
+	artifact, authorizer, err := packageStage(tempDir, &stageConfig, "")
+	if err != nil {
+		return err
+	}
+	manifest, err := json.Marshal(prebuiltManifest{
+		Stage:        stageConfig.Stage,
+		FunctionName: stageConfig.FunctionName,
+		Artifact:     artifact,
+		Authorizer:   authorizer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal prebuilt package: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "prebuilt.json"), manifest, 0644); err != nil {
+		return fmt.Errorf("failed to write prebuilt package: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the gozap executable: %w", err)
+	}
+
+	width := 0
+	for _, region := range regions {
+		width = max(width, len(region))
+	}
+
+	// 2. Run the regions through a bounded pool. Once a region failed no new
+	// region starts unless --continue-on-error is set; running ones finish.
+	results := make([]regionResult, len(regions))
+	slots := make(chan struct{}, opts.Parallel)
+	var output sync.Mutex
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		slots <- struct{}{}
+		if failed.Load() && !opts.ContinueOnError {
+			results[i] = regionResult{Region: region, Status: "⏭️  skipped"}
+			<-slots
+			continue
+		}
+
+		args := []string{command, "--stage", stageConfig.Stage, "--region", region}
+		if targetOverrides.Profile != "" {
+			args = append(args, "--profile", targetOverrides.Profile)
+		}
+		args = append(args, extraArgs...)
+		prefix := fmt.Sprintf("[%-*s] ", width, region)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			process := exec.Command(executable, args...)
+			process.Env = append(os.Environ(), prebuiltEnv+"="+tempDir)
+			err := runRegion(process, prefix, &output)
+			results[i] = regionResult{Region: region, Status: "✅ done", Duration: time.Since(start), Err: err}
+			if err != nil {
+				results[i].Status = "❌ failed"
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 3. Summarize
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REGION\tRESULT\tDURATION")
+	failures := 0
+	for _, result := range results {
+		duration := "-"
+		if result.Duration > 0 {
+			duration = result.Duration.Round(time.Second).String()
+		}
+		if result.Err != nil {
+			failures++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Region, result.Status, duration)
+	}
+	w.Flush()
+
+	if failures > 0 {
+		return fmt.Errorf("❌ %s failed in %d of %d regions", command, failures, len(regions))
+	}
+	fmt.Printf("✅ %s complete in all regions\n", command)
+	return nil
+}
+
+// runRegion runs one region's process, streaming its output line by line
+// with the region as prefix
+func runRegion(cmd *exec.Cmd, prefix string, output *sync.Mutex) error {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		reader.Close()
+		return err
+	}
+	writer.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		output.Lock()
+		fmt.Println(prefix + scanner.Text())
+		output.Unlock()
+	}
+	reader.Close()
+
+	return cmd.Wait()
+}
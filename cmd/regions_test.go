@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStageRegions(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "regions", change: func(c *DeploymentConfig) {
+			c.Regions = []string{"us-east-1", "eu-west-1"}
+			c.Buckets = map[string]string{"us-east-1": "deploys-use1", "eu-west-1": "deploys-euw1"}
+		}},
+		{name: "region and regions", change: func(c *DeploymentConfig) {
+			c.Region = "us-east-1"
+			c.Regions = []string{"eu-west-1"}
+		}, wantErr: "either Region or Regions"},
+		{name: "region listed twice", change: func(c *DeploymentConfig) { c.Regions = []string{"eu-west-1", "eu-west-1"} }, wantErr: "listed twice"},
+		{name: "invalid region", change: func(c *DeploymentConfig) { c.Regions = []string{"Europe"} }, wantErr: "not an AWS region"},
+		{name: "invalid bucket region", change: func(c *DeploymentConfig) { c.Buckets = map[string]string{"Europe": "deploys"} }, wantErr: "in Buckets"},
+	})
+}
+
+func TestRegionalConfigs(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		stage    DeploymentConfig
+		want     []string // regions of the returned stages
+	}{
+		{name: "single region", stage: DeploymentConfig{Region: "eu-west-1"}, want: []string{"eu-west-1"}},
+		{name: "one of Regions", stage: DeploymentConfig{Regions: []string{"eu-west-1"}}, want: []string{""}},
+		{name: "every region", stage: DeploymentConfig{Regions: []string{"us-east-1", "eu-west-1"}}, want: []string{"us-east-1", "eu-west-1"}},
+		{name: "--region picks one", override: "eu-west-1", stage: DeploymentConfig{Regions: []string{"us-east-1", "eu-west-1"}}, want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeTarget(t)
+			targetOverrides.Region = tt.override
+
+			var regions []string
+			for _, regional := range regionalConfigs(tt.stage) {
+				if len(tt.want) > 1 && len(regional.Regions) != 0 {
+					t.Errorf("stage for %s keeps Regions %v", regional.Region, regional.Regions)
+				}
+				regions = append(regions, regional.Region)
+			}
+			if !reflect.DeepEqual(regions, tt.want) {
+				t.Errorf("regionalConfigs() regions = %v, want %v", regions, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployedTargets(t *testing.T) {
+	useFakeTarget(t)
+	fakeAWS(t, `case "$1 $2" in
+  "cloudformation describe-stacks") [ "$AWS_REGION" = eu-west-1 ] ;;
+  *) exit 1 ;;
+esac
+`)
+
+	stageConfig := DeploymentConfig{Stage: "prod", Regions: []string{"us-east-1", "eu-west-1", "ap-northeast-1"}}
+	deployed, err := deployedTargets(stageConfig, "app-prod")
+	if err != nil {
+		t.Fatalf("deployedTargets() error = %v", err)
+	}
+	if !reflect.DeepEqual(deployed, []string{"eu-west-1"}) {
+		t.Errorf("deployedTargets() = %v, want the only region with a stack", deployed)
+	}
+}
+
+func TestLastEntryIn(t *testing.T) {
+	entries := []HistoryEntry{
+		{Action: "deploy"},
+		{Action: "update", Region: "us-east-1"},
+		{Action: "update", Region: "eu-west-1"},
+	}
+	if last := lastEntryIn(entries, "us-east-1"); last == nil || last != &entries[1] {
+		t.Errorf("lastEntryIn(us-east-1) = %+v, want the us-east-1 update", last)
+	}
+	if last := lastEntryIn(entries, "ap-northeast-1"); last == nil || last != &entries[0] {
+		t.Errorf("lastEntryIn(ap-northeast-1) = %+v, want the entry without a region", last)
+	}
+	if last := lastEntryIn(nil, "us-east-1"); last != nil {
+		t.Errorf("lastEntryIn() without entries = %+v", last)
+	}
+}
+
+func TestPrebuiltDir(t *testing.T) {
+	stageConfig := DeploymentConfig{Regions: []string{"us-east-1", "eu-west-1"}}
+	built := t.TempDir()
+	if err := os.WriteFile(filepath.Join(built, "prebuilt.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		override string
+		want     string
+		wantErr  bool
+	}{
+		{name: "not set"},
+		{name: "region process", env: built, override: "eu-west-1", want: built},
+		{name: "without --region", env: built, wantErr: true},
+		{name: "other region", env: built, override: "ap-northeast-1", wantErr: true},
+		{name: "no manifest", env: t.TempDir(), override: "eu-west-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeTarget(t)
+			t.Setenv(prebuiltEnv, tt.env)
+			targetOverrides.Region = tt.override
+
+			got, err := prebuiltDir(stageConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prebuiltDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("prebuiltDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageStagePrebuilt(t *testing.T) {
+	writePackage := func(t *testing.T, dir, name, content string) *Artifact {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &Artifact{Path: path, Sha256: hash}
+	}
+	writeManifest := func(t *testing.T, dir string, manifest prebuiltManifest) {
+		t.Helper()
+		content, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "prebuilt.json"), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, dir string, manifest *prebuiltManifest)
+		wantErr string
+	}{
+		{name: "matching package"},
+		{name: "other stage", change: func(t *testing.T, dir string, m *prebuiltManifest) { m.Stage = "dev" }, wantErr: "was not built for stage"},
+		{name: "other project", change: func(t *testing.T, dir string, m *prebuiltManifest) { m.FunctionName = "other-prod" }, wantErr: "was not built for stage"},
+		{name: "no artifact", change: func(t *testing.T, dir string, m *prebuiltManifest) { m.Artifact = nil }, wantErr: "was not built for stage"},
+		{name: "changed package", change: func(t *testing.T, dir string, m *prebuiltManifest) {
+			if err := os.WriteFile(m.Artifact.Path, []byte("tampered"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, wantErr: "changed since it was built"},
+		{name: "missing authorizer", change: func(t *testing.T, dir string, m *prebuiltManifest) {
+			if err := os.Remove(m.Authorizer.Path); err != nil {
+				t.Fatal(err)
+			}
+		}, wantErr: "missing or changed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest := prebuiltManifest{
+				Stage:        "prod",
+				FunctionName: "app-prod",
+				Artifact:     writePackage(t, dir, "deployment.zip", "function"),
+				Authorizer:   writePackage(t, dir, "authorizer.zip", "authorizer"),
+			}
+			if tt.change != nil {
+				tt.change(t, dir, &manifest)
+			}
+			writeManifest(t, dir, manifest)
+
+			stageConfig := DeploymentConfig{Stage: "prod", FunctionName: "app-prod"}
+			artifact, authorizer, err := packageStage(t.TempDir(), &stageConfig, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("packageStage() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("packageStage() error = %v", err)
+			}
+			if artifact.Sha256 != manifest.Artifact.Sha256 || authorizer.Sha256 != manifest.Authorizer.Sha256 {
+				t.Errorf("packageStage() = %+v, %+v, want the prebuilt packages", artifact, authorizer)
+			}
+			if stageConfig.AuthorizerCodeSha256 != manifest.Authorizer.Sha256 {
+				t.Errorf("AuthorizerCodeSha256 = %s, want the prebuilt authorizer's hash", stageConfig.AuthorizerCodeSha256)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/scaffold
+var scaffoldFS embed.FS
+
+// frameworkAdapters maps each supported web framework to the Lambda adapter
+// package its scaffold is wired to
+var frameworkAdapters = map[string]string{
+	"gin":     "github.com/awslabs/aws-lambda-go-api-proxy/gin",
+	"echo":    "github.com/awslabs/aws-lambda-go-api-proxy/echo",
+	"chi":     "github.com/awslabs/aws-lambda-go-api-proxy/chi",
+	"fiber":   "github.com/awslabs/aws-lambda-go-api-proxy/fiber",
+	"nethttp": "github.com/awslabs/aws-lambda-go-api-proxy/httpadapter",
+}
+
+// scaffoldFiles maps each scaffold template to the project file it generates.
+// %s is replaced with the framework for framework specific templates.
+// Optional templates only exist for some frameworks.
+var scaffoldFiles = []struct {
+	Template string
+	File     string
+	Optional bool
+}{
+	{"templates/scaffold/%s/main.go.tmpl", "main.go", false},
+	{"templates/scaffold/%s/go.mod.tmpl", "go.mod", false},
+	{"templates/scaffold/%s/tracing.go.tmpl", "tracing.go", true},
+	{"templates/scaffold/events.go.tmpl", "events.go", false},
+	{"templates/scaffold/Makefile.tmpl", "Makefile", false},
+}
+
+// scaffoldProject generates a working Lambda application for the selected
+// framework in the current directory. Existing files are never overwritten.
+func scaffoldProject(opts *InitOptions) error {
+	fmt.Printf("🏗️  Generating %s project files...\n", opts.Framework)
+
+	for _, f := range scaffoldFiles {
+		name := f.Template
+		if strings.Contains(name, "%s") {
+			name = fmt.Sprintf(name, opts.Framework)
+		}
+
+		if _, err := fs.Stat(scaffoldFS, name); err != nil && f.Optional {
+			continue
+		}
+
+		if _, err := os.Stat(f.File); err == nil {
+			fmt.Printf("  - %s already exists, skipping\n", f.File)
+			continue
+		}
+
+		content, err := renderScaffold(name, opts)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(f.File, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.File, err)
+		}
+		fmt.Printf("  - %s created\n", f.File)
+	}
+
+	return updateGitignore(".gitignore", opts)
+}
+
+func renderScaffold(name string, opts *InitOptions) ([]byte, error) {
+	templateContent, err := scaffoldFS.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	tmpl, err := template.New(name).Parse(string(templateContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, opts); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// updateGitignore appends the scaffold's ignore entries that are missing
+func updateGitignore(path string, opts *InitOptions) error {
+	content, err := renderScaffold("templates/scaffold/gitignore.tmpl", opts)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	present := map[string]bool{}
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if !present[line] {
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		missing[0] = "\n" + missing[0]
+	}
+	if _, err := file.WriteString(strings.Join(missing, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	fmt.Printf("  - %s updated\n", path)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var scheduleMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// Schedule invokes an HTTP route of the function on a cron or rate
+// expression through an EventBridge rule
+type Schedule struct {
+	Expression string // cron(...) or rate(...)
+	Method     string
+	Path       string
+	Body       string `json:",omitempty"`
+}
+
+func (s Schedule) validate() error {
+	if !strings.HasPrefix(s.Expression, "cron(") && !strings.HasPrefix(s.Expression, "rate(") {
+		return fmt.Errorf("❌ schedule expression '%s' must be cron(...) or rate(...)", s.Expression)
+	}
+	if !slices.Contains(scheduleMethods, strings.ToUpper(s.Method)) {
+		return fmt.Errorf("❌ schedule method '%s' must be one of %s", s.Method, strings.Join(scheduleMethods, ", "))
+	}
+	if !strings.HasPrefix(s.Path, "/") {
+		return fmt.Errorf("❌ schedule path '%s' must start with /", s.Path)
+	}
+	return nil
+}
+
+// Description returns the rule description, quoted for use as a YAML scalar
+func (s Schedule) Description() string {
+	quoted, _ := json.Marshal(fmt.Sprintf("%s %s on %s (GoZap)", strings.ToUpper(s.Method), s.Path, s.Expression))
+	return string(quoted)
+}
+
+// Input returns the API Gateway proxy event the rule sends to the function,
+// quoted for use as a YAML scalar. The event goes through the same router as
+// regular HTTP requests.
+func (s Schedule) Input(stage string) string {
+	method := strings.ToUpper(s.Method)
+	path, rawQuery, _ := strings.Cut(s.Path, "?")
+
+	var query map[string]string
+	if values, err := url.ParseQuery(rawQuery); err == nil && len(values) > 0 {
+		query = map[string]string{}
+		for key := range values {
+			query[key] = values.Get(key)
+		}
+	}
+
+	headers := map[string]string{"User-Agent": "gozap-scheduler"}
+	if s.Body != "" {
+		headers["Content-Type"] = "application/json"
+	}
+
+	event := map[string]any{
+		"resource":              "/{proxy+}",
+		"path":                  path,
+		"httpMethod":            method,
+		"headers":               headers,
+		"queryStringParameters": query,
+		"pathParameters":        map[string]string{"proxy": strings.TrimPrefix(path, "/")},
+		"requestContext": map[string]any{
+			"stage":        stage,
+			"path":         "/" + stage + path,
+			"httpMethod":   method,
+			"resourcePath": "/{proxy+}",
+			"identity":     map[string]string{"userAgent": "gozap-scheduler"},
+		},
+		"body":            s.Body,
+		"isBase64Encoded": false,
+	}
+
+	content, _ := json.Marshal(event)
+	quoted, _ := json.Marshal(string(content))
+	return string(quoted)
+}
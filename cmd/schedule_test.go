@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateStageSchedules(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "schedule", change: func(c *DeploymentConfig) {
+			c.Schedules = []Schedule{{Expression: "cron(0 12 * * ? *)", Method: "post", Path: "/jobs"}}
+		}},
+		{name: "schedule expression", change: func(c *DeploymentConfig) {
+			c.Schedules = []Schedule{{Expression: "every hour", Method: "GET", Path: "/"}}
+		}, wantErr: "cron(...) or rate(...)"},
+		{name: "schedule method", change: func(c *DeploymentConfig) {
+			c.Schedules = []Schedule{{Expression: "rate(1 hour)", Method: "FETCH", Path: "/"}}
+		}, wantErr: "schedule method"},
+		{name: "schedule path", change: func(c *DeploymentConfig) {
+			c.Schedules = []Schedule{{Expression: "rate(1 hour)", Method: "GET", Path: "jobs"}}
+		}, wantErr: "must start with /"},
+	})
+}
+
+func TestScheduleTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Schedules = []Schedule{
+		{Expression: "rate(5 minutes)", Method: "GET", Path: "/jobs/cleanup"},
+		{Expression: "cron(0 3 * * ? *)", Method: "POST", Path: "/reports?format=csv", Body: `{"full": true}`},
+	}
+	template := renderTemplate(t, stageConfig)
+
+	for i, schedule := range stageConfig.Schedules {
+		var rule struct {
+			ScheduleExpression string `yaml:"ScheduleExpression"`
+			Targets            []struct {
+				Input string `yaml:"Input"`
+			} `yaml:"Targets"`
+		}
+		name := fmt.Sprintf("Schedule%d", i)
+		template.resource(t, name, "AWS::Events::Rule", &rule)
+		if rule.ScheduleExpression != schedule.Expression {
+			t.Errorf("%s ScheduleExpression = %q, want %q", name, rule.ScheduleExpression, schedule.Expression)
+		}
+		if len(rule.Targets) != 1 || !json.Valid([]byte(rule.Targets[0].Input)) {
+			t.Errorf("%s does not send one JSON event: %+v", name, rule.Targets)
+		}
+		template.resource(t, name+"Permission", "AWS::Lambda::Permission", nil)
+	}
+}
+
+func TestScheduleInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		schedule  Schedule
+		wantPath  string
+		wantQuery map[string]string
+		wantType  bool // whether a Content-Type header is sent
+	}{
+		{
+			name:     "plain route",
+			schedule: Schedule{Expression: "rate(5 minutes)", Method: "get", Path: "/jobs/cleanup"},
+			wantPath: "/jobs/cleanup",
+		},
+		{
+			name:      "query string",
+			schedule:  Schedule{Expression: "rate(1 hour)", Method: "GET", Path: "/reports?format=csv&days=7"},
+			wantPath:  "/reports",
+			wantQuery: map[string]string{"format": "csv", "days": "7"},
+		},
+		{
+			name:     "body",
+			schedule: Schedule{Expression: "cron(0 3 * * ? *)", Method: "POST", Path: "/jobs", Body: `{"full": true}`},
+			wantPath: "/jobs",
+			wantType: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Input is a quoted YAML scalar holding the JSON event
+			var content string
+			if err := json.Unmarshal([]byte(tt.schedule.Input("prod")), &content); err != nil {
+				t.Fatalf("Input() is not a quoted string: %v", err)
+			}
+			var event struct {
+				Path                  string            `json:"path"`
+				HTTPMethod            string            `json:"httpMethod"`
+				Headers               map[string]string `json:"headers"`
+				QueryStringParameters map[string]string `json:"queryStringParameters"`
+				PathParameters        map[string]string `json:"pathParameters"`
+				Body                  string            `json:"body"`
+				RequestContext        struct {
+					Stage string `json:"stage"`
+					Path  string `json:"path"`
+				} `json:"requestContext"`
+			}
+			if err := json.Unmarshal([]byte(content), &event); err != nil {
+				t.Fatalf("Input() does not hold a JSON event: %v", err)
+			}
+
+			if event.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", event.Path, tt.wantPath)
+			}
+			if event.HTTPMethod != "GET" && event.HTTPMethod != "POST" {
+				t.Errorf("httpMethod = %q, want it upper case", event.HTTPMethod)
+			}
+			if !reflect.DeepEqual(event.QueryStringParameters, tt.wantQuery) {
+				t.Errorf("queryStringParameters = %v, want %v", event.QueryStringParameters, tt.wantQuery)
+			}
+			if event.PathParameters["proxy"] != tt.wantPath[1:] {
+				t.Errorf("proxy path parameter = %q, want %q", event.PathParameters["proxy"], tt.wantPath[1:])
+			}
+			if event.Body != tt.schedule.Body {
+				t.Errorf("body = %q, want %q", event.Body, tt.schedule.Body)
+			}
+			if _, found := event.Headers["Content-Type"]; found != tt.wantType {
+				t.Errorf("Content-Type header sent = %v, want %v", found, tt.wantType)
+			}
+			if event.RequestContext.Stage != "prod" || event.RequestContext.Path != "/prod"+tt.wantPath {
+				t.Errorf("requestContext = %+v, want stage prod and path /prod%s", event.RequestContext, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func NewStageCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stage",
+		Short: "Manage the stages in config.json",
+		Long:  `List, add, copy, update and remove the stages configured in config.json.`,
+	}
+
+	cmd.AddCommand(newStageListCommand())
+	cmd.AddCommand(newStageAddCommand())
+	cmd.AddCommand(newStageCopyCommand())
+	cmd.AddCommand(newStageSetCommand())
+	cmd.AddCommand(newStageRemoveCommand())
+
+	return cmd
+}
+
+func newStageListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the configured stages and their deployment status",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStageList()
+		},
+	}
+}
+
+func newStageAddCommand() *cobra.Command {
+	opts := &StageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "add <stage>",
+		Short: "Add a new stage",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStageAdd(args[0], opts)
+		},
+	}
+
+	addStageFlags(cmd, opts)
+	return cmd
+}
+
+func newStageCopyCommand() *cobra.Command {
+	opts := &StageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "copy <from> <to>",
+		Short: "Create a stage from the configuration of another one",
+		Long:  `Create a stage from the configuration of another one. Flags override the copied values.`,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStageCopy(cmd, args[0], args[1], opts)
+		},
+	}
+
+	addStageFlags(cmd, opts)
+	return cmd
+}
+
+func newStageSetCommand() *cobra.Command {
+	opts := &StageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "set <stage>",
+		Short: "Update fields of a stage",
+		Long:  `Update fields of a stage. Run 'gozap update' afterwards to apply them to the deployed stack.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStageSet(cmd, args[0], opts)
+		},
+	}
+
+	addStageFlags(cmd, opts)
+	return cmd
+}
+
+func newStageRemoveCommand() *cobra.Command {
+	opts := &StageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "remove <stage>",
+		Short: "Remove a stage from config.json",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStageRemove(args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Undeploy, "undeploy", false, "Also delete the deployed CloudFormation stack")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation prompts")
+
+	return cmd
+}
+
+// addStageFlags registers the stage fields that can be set from flags
+func addStageFlags(cmd *cobra.Command, opts *StageOptions) {
+	cmd.Flags().StringVarP(&opts.S3Bucket, "bucket", "b", "", "S3 bucket for deployment artifacts")
+	cmd.Flags().IntVarP(&opts.Timeout, "timeout", "t", 30, "Lambda function timeout in seconds")
+	cmd.Flags().IntVarP(&opts.Memory, "memory", "m", 128, "Lambda function memory in MB")
+}
+
+func runStageList() error {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	stages := make([]string, 0, len(config))
+	for stage := range config {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tFUNCTION\tREGION\tBUCKET\tMEMORY\tTIMEOUT\tDEPLOYED")
+	for _, stage := range stages {
+		stageConfig := config[stage]
+		stageConfig.Stage = stage
+
+		// Stages deployed to several regions get a row per region
+		for _, target := range regionalConfigs(stageConfig) {
+			status := "not deployed"
+			bucket := target.S3Bucket
+			if regional, found := target.Buckets[target.Region]; found {
+				bucket = regional
+			}
+			if err := switchTarget(target); err != nil {
+				status = "unknown (no access)"
+			} else if stack, err := describeStack(fmt.Sprintf("%s-%s", target.FunctionName, stage)); err == nil {
+				status = stack.StackStatus
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d MB\t%ds\t%s\n",
+				stage, target.FunctionName, currentTarget.Region, bucket, target.Memory, target.Timeout, status)
+		}
+	}
+	return w.Flush()
+}
+
+func runStageAdd(stage string, opts *StageOptions) error {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	if _, exists := config[stage]; exists {
+		return fmt.Errorf("❌ stage '%s' already exists in config.json", stage)
+	}
+
+	project, err := projectName(config)
+	if err != nil {
+		return err
+	}
+
+	stageConfig := DeploymentConfig{
+		FunctionName: fmt.Sprintf("%s-%s", project, stage),
+		S3Bucket:     opts.S3Bucket,
+		Timeout:      opts.Timeout,
+		Memory:       opts.Memory,
+		Stage:        stage,
+		Region:       targetOverrides.Region,
+		Profile:      targetOverrides.Profile,
+	}
+	if stageConfig.S3Bucket == "" {
+		stageConfig.S3Bucket = bootstrapBucket(defaultRegion())
+	}
+	if stageConfig.S3Bucket == "" {
+		return fmt.Errorf("❌ S3 bucket is required, pass --bucket or run 'gozap bootstrap' first")
+	}
+
+	if err := validateStageConfig(stageConfig); err != nil {
+		return err
+	}
+	if err := checkStageBucket(stageConfig); err != nil {
+		return err
+	}
+
+	config[stage] = stageConfig
+	if err := writeConfig("config.json", config); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stage '%s' added\n", stage)
+	fmt.Printf("  Run 'gozap deploy --stage %s' to deploy it\n", stage)
+	return nil
+}
+
+func runStageCopy(cmd *cobra.Command, from, to string, opts *StageOptions) error {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	source, exists := config[from]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", from)
+	}
+	if _, exists := config[to]; exists {
+		return fmt.Errorf("❌ stage '%s' already exists in config.json", to)
+	}
+
+	// Copy everything but the deployment state, then apply the overrides
+	stageConfig := source
+	stageConfig.FunctionName = fmt.Sprintf("%s-%s", strings.TrimSuffix(source.FunctionName, "-"+from), to)
+	stageConfig.Stage = to
+	stageConfig.S3Key = ""
+	stageConfig.CodeSha256 = ""
+
+	if err := applyStageFlags(cmd, &stageConfig, opts); err != nil {
+		return err
+	}
+
+	config[to] = stageConfig
+	if err := writeConfig("config.json", config); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stage '%s' copied to '%s'\n", from, to)
+	fmt.Printf("  Run 'gozap deploy --stage %s' to deploy it\n", to)
+	return nil
+}
+
+func runStageSet(cmd *cobra.Command, stage string, opts *StageOptions) error {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	stageConfig, exists := config[stage]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", stage)
+	}
+
+	if !cmd.Flags().Changed("bucket") && !cmd.Flags().Changed("memory") && !cmd.Flags().Changed("timeout") {
+		return fmt.Errorf("❌ nothing to set, pass at least one of --bucket, --memory or --timeout")
+	}
+
+	if err := applyStageFlags(cmd, &stageConfig, opts); err != nil {
+		return err
+	}
+
+	config[stage] = stageConfig
+	if err := writeConfig("config.json", config); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stage '%s' updated\n", stage)
+	fmt.Printf("  Run 'gozap update --stage %s' to apply the changes\n", stage)
+	return nil
+}
+
+func runStageRemove(stage string, opts *StageOptions) error {
+	config, err := readConfig("config.json")
+	if err != nil {
+		return err
+	}
+
+	stageConfig, exists := config[stage]
+	if !exists {
+		return fmt.Errorf("❌ stage '%s' not found in configuration", stage)
+	}
+
+	stageConfig.Stage = stage
+	stackName := fmt.Sprintf("%s-%s", stageConfig.FunctionName, stage)
+
+	// The stage is removed for all of its regions, so --region cannot narrow
+	// the check to one of them
+	if targetOverrides.Region != "" && len(stageConfig.Regions) > 1 {
+		return fmt.Errorf("❌ stage '%s' is removed from all of its regions, drop --region", stage)
+	}
+	deployed, err := deployedTargets(stageConfig, stackName)
+	if err != nil {
+		return err
+	}
+
+	if len(deployed) > 0 && opts.Undeploy {
+		if err := runUndeploy(&UndeployOptions{Stage: stage, Force: opts.Force}); err != nil {
+			return err
+		}
+		// Undeploy may have been cancelled at its prompt
+		if deployed, err := deployedTargets(stageConfig, stackName); err != nil || len(deployed) > 0 {
+			return err
+		}
+	} else if len(deployed) > 0 {
+		fmt.Printf("⚠️  Stack '%s' is still deployed in %s and will no longer be managed by GoZap\n", stackName, strings.Join(deployed, "; "))
+		fmt.Println("   Use --undeploy to delete it as well")
+		if !opts.Force && !confirmAction("Remove the stage anyway?") {
+			fmt.Println("❌ Stage removal cancelled")
+			return nil
+		}
+	}
+
+	delete(config, stage)
+	if err := writeConfig("config.json", config); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stage '%s' removed from config.json\n", stage)
+	return nil
+}
+
+// deployedTargets returns the targets of the stage its stack is deployed in
+func deployedTargets(stageConfig DeploymentConfig, stackName string) ([]string, error) {
+	var targets []string
+	for _, regional := range regionalConfigs(stageConfig) {
+		if err := switchTarget(regional); err != nil {
+			return nil, err
+		}
+		if checkStackExists(stackName) == nil {
+			targets = append(targets, currentTarget.String())
+		}
+	}
+	return targets, nil
+}
+
+// applyStageFlags copies the stage flags that were set onto the stage and
+// validates the result
+func applyStageFlags(cmd *cobra.Command, stageConfig *DeploymentConfig, opts *StageOptions) error {
+	if cmd.Flags().Changed("memory") {
+		stageConfig.Memory = opts.Memory
+	}
+	if cmd.Flags().Changed("timeout") {
+		stageConfig.Timeout = opts.Timeout
+	}
+	if err := validateStageConfig(*stageConfig); err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("bucket") {
+		stageConfig.S3Bucket = opts.S3Bucket
+		return checkStageBucket(*stageConfig)
+	}
+	return nil
+}
+
+// validateStageConfig checks the stage values against the Lambda limits
+func validateStageConfig(stageConfig DeploymentConfig) error {
+	if stageConfig.Memory < 128 || stageConfig.Memory > 10240 {
+		return fmt.Errorf("❌ memory must be between 128 and 10240 MB, got %d", stageConfig.Memory)
+	}
+	if stageConfig.Timeout < 1 || stageConfig.Timeout > 900 {
+		return fmt.Errorf("❌ timeout must be between 1 and 900 seconds, got %d", stageConfig.Timeout)
+	}
+	for _, schedule := range stageConfig.Schedules {
+		if err := schedule.validate(); err != nil {
+			return err
+		}
+	}
+	for _, rule := range stageConfig.Include {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Package != "" && stageConfig.Package != "zip" && stageConfig.Package != "image" {
+		return fmt.Errorf("❌ Package must be 'zip' or 'image', got '%s'", stageConfig.Package)
+	}
+	if stageConfig.IsImage() && len(stageConfig.Layers) > 0 {
+		return fmt.Errorf("❌ container images cannot use layers, add the files to the image with Include instead")
+	}
+	if len(stageConfig.Layers) > 5 {
+		return fmt.Errorf("❌ a function can use at most 5 layers, got %d", len(stageConfig.Layers))
+	}
+	for _, layer := range stageConfig.Layers {
+		if !strings.HasPrefix(layer, "arn:") || !strings.Contains(layer, ":layer:") {
+			return fmt.Errorf("❌ '%s' is not a layer version ARN", layer)
+		}
+	}
+	if stageConfig.Vpc != nil {
+		if err := stageConfig.Vpc.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Events != nil {
+		if err := stageConfig.Events.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Cors != nil {
+		if err := stageConfig.Cors.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Auth != nil {
+		if err := stageConfig.Auth.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Throttle != nil {
+		if err := stageConfig.Throttle.validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateUsagePlans(stageConfig.UsagePlans); err != nil {
+		return err
+	}
+	if stageConfig.Waf != nil {
+		if err := stageConfig.Waf.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Monitoring != nil {
+		if err := stageConfig.Monitoring.validate(); err != nil {
+			return err
+		}
+	}
+	if stageConfig.Tracing != nil {
+		if err := stageConfig.Tracing.validate(stageConfig); err != nil {
+			return err
+		}
+	}
+	if err := validateTags(stageConfig.Tags); err != nil {
+		return err
+	}
+	if err := validateTarget(stageConfig); err != nil {
+		return err
+	}
+	if err := validateRegions(stageConfig); err != nil {
+		return err
+	}
+	if err := validateConcurrency(stageConfig); err != nil {
+		return err
+	}
+	return nil
+}
+
+// projectName derives the project name from the function names of the
+// existing stages, which init creates as <project>-<stage>
+func projectName(config map[string]DeploymentConfig) (string, error) {
+	for stage, stageConfig := range config {
+		if name, found := strings.CutSuffix(stageConfig.FunctionName, "-"+stage); found {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("❌ cannot determine the project name from config.json, use 'gozap init' instead")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func validStage() DeploymentConfig {
+	return DeploymentConfig{FunctionName: "app-dev", Stage: "dev", S3Bucket: "bucket", Memory: 128, Timeout: 30}
+}
+
+// stageCase changes a valid stage and names the error validateStageConfig
+// should report for it
+type stageCase struct {
+	name    string
+	change  func(c *DeploymentConfig)
+	wantErr string // substring of the error, empty when the stage is valid
+}
+
+func runStageCases(t *testing.T, tests []stageCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageConfig := validStage()
+			tt.change(&stageConfig)
+			err := validateStageConfig(stageConfig)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateStageConfig() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateStageConfig() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageConfig(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "minimal", change: func(c *DeploymentConfig) {}},
+		{name: "largest", change: func(c *DeploymentConfig) {
+			c.Memory = 10240
+			c.Timeout = 900
+		}},
+		{name: "memory too low", change: func(c *DeploymentConfig) { c.Memory = 64 }, wantErr: "memory"},
+		{name: "memory too high", change: func(c *DeploymentConfig) { c.Memory = 10241 }, wantErr: "memory"},
+		{name: "timeout zero", change: func(c *DeploymentConfig) { c.Timeout = 0 }, wantErr: "timeout"},
+		{name: "timeout too high", change: func(c *DeploymentConfig) { c.Timeout = 901 }, wantErr: "timeout"},
+	})
+}
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]DeploymentConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "one stage", config: map[string]DeploymentConfig{"dev": {FunctionName: "shop-dev"}}, want: "shop"},
+		{name: "dashes in the project", config: map[string]DeploymentConfig{"prod": {FunctionName: "my-shop-prod"}}, want: "my-shop"},
+		{name: "renamed function", config: map[string]DeploymentConfig{"dev": {FunctionName: "legacy"}}, wantErr: true},
+		{name: "no stages", config: map[string]DeploymentConfig{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := projectName(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("projectName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("projectName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func NewStatusCommand() *cobra.Command {
+	opts := &StatusOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the deployment status of a stage",
+		Long:  `Show the CloudFormation stack status, Lambda function configuration, deployed artifact and API endpoint of a stage.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+func runStatus(opts *StatusOptions) error {
+	// 1. Read the stage from config.json
+	stageConfig, stackName, err := loadStage(opts.Stage)
+	if err != nil {
+		return err
+	}
+
+	// A stage with several Regions shows every region unless --region picks one
+	for _, regional := range regionalConfigs(stageConfig) {
+		if err := printStatus(regional, stackName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printStatus shows the stack, function and alias of the stage in the
+// stage's region
+func printStatus(stageConfig DeploymentConfig, stackName string) error {
+	// Point the aws CLI at the stage's account and region
+	if err := useTarget(&stageConfig); err != nil {
+		return err
+	}
+
+	fmt.Printf("📊 Status of stage '%s'\n", stageConfig.Stage)
+
+	// 2. Stack status and endpoint
+	stack, err := describeStack(stackName)
+	if err != nil {
+		fmt.Printf("  Stack: %s (not deployed)\n", stackName)
+		return nil
+	}
+	fmt.Printf("  Stack: %s\n", stackName)
+	fmt.Printf("  Stack Status: %s\n", stack.StackStatus)
+	if endpoint, ok := stack.stackOutputs()["ApiEndpoint"]; ok {
+		fmt.Printf("  Endpoint: %s\n", endpoint)
+	}
+
+	// 3. Function configuration
+	function, err := getFunctionConfiguration(stackName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Function: %s (%s, %s)\n", function.FunctionName, function.Runtime, function.State)
+	fmt.Printf("  Memory: %d MB\n", function.MemorySize)
+	fmt.Printf("  Timeout: %d seconds\n", function.Timeout)
+	fmt.Printf("  Last Modified: %s\n", function.LastModified)
+
+	// 4. Deployed artifact and alias
+	if artifact, err := deployedArtifact(stackName); err == nil {
+		fmt.Printf("  Artifact: %s\n", artifact.Key())
+	} else {
+		fmt.Printf("  Artifact: unknown (code sha256 %s)\n", function.CodeSha256)
+	}
+	if alias, err := getAlias(stackName, stageConfig.Stage); err == nil {
+		if pending, weight := alias.pendingVersion(); pending != "" {
+			fmt.Printf("  Alias: %s → version %s, %.0f%% shifted to version %s\n", stageConfig.Stage, alias.FunctionVersion, weight*100, pending)
+		} else {
+			fmt.Printf("  Alias: %s → version %s\n", stageConfig.Stage, alias.FunctionVersion)
+		}
+	}
+
+	// 5. Last recorded deployment
+	entries, err := readHistory(historyFile, stageConfig.Stage)
+	if last := lastEntryIn(entries, currentTarget.Region); err == nil && last != nil {
+		fmt.Printf("  Last %s: %s by %s\n", last.Action, last.Timestamp.Local().Format("2006-01-02 15:04:05"), last.User)
+	}
+
+	return nil
+}
+
+// lastEntryIn returns the last history entry of the region. Entries recorded
+// without a region count for every region.
+func lastEntryIn(entries []HistoryEntry, region string) *HistoryEntry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Region == "" || entries[i].Region == region {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+type functionConfiguration struct {
+	FunctionName string `json:"FunctionName"`
+	Runtime      string `json:"Runtime"`
+	State        string `json:"State"`
+	MemorySize   int    `json:"MemorySize"`
+	Timeout      int    `json:"Timeout"`
+	LastModified string `json:"LastModified"`
+	CodeSha256   string `json:"CodeSha256"`
+}
+
+func getFunctionConfiguration(functionName string) (*functionConfiguration, error) {
+	cmd := awsCommand("lambda", "get-function-configuration", "--function-name", functionName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function configuration: %w\n%s", err, output)
+	}
+
+	var config functionConfiguration
+	if err := json.Unmarshal(output, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse function configuration: %w", err)
+	}
+	return &config, nil
+}
@@ -1,11 +1,78 @@
 package cmd
 
+import "time"
+
 type DeployOptions struct {
-	Stage string
+	Stage       string
+	MultiRegion MultiRegionOptions
 }
 
 type UpdateOptions struct {
+	Stage       string
+	Canary      string
+	Linear      string
+	MultiRegion MultiRegionOptions
+}
+
+// MultiRegionOptions controls how a stage with several Regions is deployed
+type MultiRegionOptions struct {
+	Parallel        int
+	ContinueOnError bool
+}
+
+type AliasOptions struct {
+	Stage string
+}
+
+type PromoteOptions struct {
+	Stage string
+	From  string
+	To    string
+}
+
+type StatusOptions struct {
+	Stage string
+}
+
+type HistoryOptions struct {
+	Stage string
+	Limit int
+}
+
+type LockOptions struct {
+	Stage string
+	Force bool
+}
+
+type ApiKeyOptions struct {
 	Stage string
+	Name  string
+	Plan  string
+	Force bool
+}
+
+type DoctorOptions struct {
+	Stage       string
+	StageConfig DeploymentConfig // loaded from config.json when Stage is set
+	TagPolicy   string
+}
+
+type BootstrapOptions struct {
+	Region        string // resolved from the root --region flag
+	RetentionDays int
+}
+
+type StageOptions struct {
+	S3Bucket string
+	Timeout  int
+	Memory   int
+	Undeploy bool
+	Force    bool
+}
+
+type TrafficShift struct {
+	Percent  int
+	Interval time.Duration // zero for canary, step interval for linear
 }
 
 type UndeployOptions struct {
@@ -19,13 +86,51 @@ type InitOptions struct {
 	S3Bucket    string
 	Timeout     int
 	Memory      int
+	Scaffold    bool
+	Framework   string
+
+	NonInteractive bool
+	Interactive    bool // set when values were collected by the wizard
 }
 
 type DeploymentConfig struct {
 	FunctionName string
 	S3Bucket     string
 	S3Key        string
+	CodeSha256   string `json:",omitempty"`
 	Timeout      int
 	Memory       int
 	Stage        string
+	Schedules    []Schedule        `json:",omitempty"`
+	Events       *EventsConfig     `json:",omitempty"`
+	Vpc          *VpcConfig        `json:",omitempty"`
+	Include      []IncludeRule     `json:",omitempty"`
+	Layers       []string          `json:",omitempty"`
+	Package      string            `json:",omitempty"` // "zip" (default) or "image"
+	Cors         *CorsConfig       `json:",omitempty"`
+	Auth         *AuthConfig       `json:",omitempty"`
+	Throttle     *ThrottleConfig   `json:",omitempty"`
+	UsagePlans   []UsagePlan       `json:",omitempty"`
+	Waf          *WafConfig        `json:",omitempty"`
+	Monitoring   *MonitoringConfig `json:",omitempty"`
+	Tracing      *TracingConfig    `json:",omitempty"`
+	Tags         map[string]string `json:",omitempty"`
+
+	// Cold start mitigation
+	ReservedConcurrency    *int          `json:",omitempty"`
+	ProvisionedConcurrency int           `json:",omitempty"` // applied to the stage alias
+	Warmer                 *WarmerConfig `json:",omitempty"`
+
+	// Account and region of the stage, the aws CLI defaults apply when unset
+	Region        string            `json:",omitempty"`
+	Regions       []string          `json:",omitempty"` // deploys the stage to each region
+	Buckets       map[string]string `json:",omitempty"` // deployment bucket per region
+	Profile       string            `json:",omitempty"`
+	AssumeRoleArn string            `json:",omitempty"`
+	ExternalId    string            `json:",omitempty"`
+	SessionName   string            `json:",omitempty"`
+
+	// Set at deploy time only, never persisted to config.json
+	ImageUri             string `json:"-"`
+	AuthorizerCodeSha256 string `json:"-"`
 }
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// projectTagsFile holds the tags shared by every stage of the project
+	projectTagsFile = "tags.json"
+	// tagPolicyFile is the default org policy doctor checks the tags against
+	tagPolicyFile = "tag-policy.json"
+	// maxStackTags is the CloudFormation limit on stack tags
+	maxStackTags = 50
+)
+
+// tagPattern is the character set CloudFormation accepts in tag keys and values
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// TagPolicy lists the tag keys an organization requires on every stack and,
+// optionally, the values allowed for some of them
+type TagPolicy struct {
+	RequiredKeys  []string
+	AllowedValues map[string][]string `json:",omitempty"`
+}
+
+// validateTags checks user defined tags. The gozap: prefix is reserved for
+// the default tags and aws: for AWS itself.
+func validateTags(tags map[string]string) error {
+	for key, value := range tags {
+		if key == "" || len(key) > 128 {
+			return fmt.Errorf("❌ tag key '%s' must be between 1 and 128 characters", key)
+		}
+		if len(value) > 256 {
+			return fmt.Errorf("❌ value of tag '%s' must be at most 256 characters", key)
+		}
+		if !tagPattern.MatchString(key) || !tagPattern.MatchString(value) {
+			return fmt.Errorf("❌ tag '%s=%s' may only contain letters, numbers, spaces and _.:/=+-@", key, value)
+		}
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "aws:") || strings.HasPrefix(lower, "gozap:") {
+			return fmt.Errorf("❌ tag key '%s' uses a reserved prefix (aws: or gozap:)", key)
+		}
+	}
+	return nil
+}
+
+// readProjectTags reads the project level tags, a missing file means no tags
+func readProjectTags(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	tags := map[string]string{}
+	if err := json.Unmarshal(content, &tags); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, fmt.Errorf("%w (in %s)", err, file)
+	}
+	return tags, nil
+}
+
+// stackTags returns the tags of a stage's stack: the gozap defaults, then the
+// project tags, then the stage tags. CloudFormation propagates stack tags to
+// the resources it creates, so they end up on the function, API and alarms.
+func stackTags(stageConfig DeploymentConfig) (map[string]string, error) {
+	projectTags, err := readProjectTags(projectTagsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for key, value := range projectTags {
+		tags[key] = value
+	}
+	for key, value := range stageConfig.Tags {
+		tags[key] = value
+	}
+
+	project, _ := strings.CutSuffix(stageConfig.FunctionName, "-"+stageConfig.Stage)
+	tags["gozap:project"] = project
+	tags["gozap:stage"] = stageConfig.Stage
+	tags["gozap:version"] = tagVersion()
+	if sha, dirty := gitRevision(); sha != "" {
+		if dirty {
+			sha += "-dirty"
+		}
+		tags["gozap:git-sha"] = sha
+	}
+
+	if len(tags) > maxStackTags {
+		return nil, fmt.Errorf("❌ a stack can have at most %d tags, got %d", maxStackTags, len(tags))
+	}
+	return tags, nil
+}
+
+// tagVersion returns the gozap version without the pre-release note, which
+// has characters tag values do not allow
+func tagVersion() string {
+	if fields := strings.Fields(version); len(fields) > 0 {
+		return fields[0]
+	}
+	return "dev"
+}
+
+// tagKeys returns the keys of tags in a stable order
+func tagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deployTagArgs formats tags for 'aws cloudformation deploy --tags'
+func deployTagArgs(tags map[string]string) []string {
+	var args []string
+	for _, key := range tagKeys(tags) {
+		args = append(args, key+"="+tags[key])
+	}
+	return args
+}
+
+// updateTagArg formats tags for 'aws cloudformation update-stack --tags'.
+// JSON avoids the shorthand syntax choking on '=' and ':' in values.
+func updateTagArg(tags map[string]string) (string, error) {
+	type tag struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	}
+
+	list := []tag{}
+	for _, key := range tagKeys(tags) {
+		list = append(list, tag{Key: key, Value: tags[key]})
+	}
+	content, err := json.Marshal(list)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	return string(content), nil
+}
+
+// sameTags compares the tags of a deployed stack with the ones about to be
+// applied. The git SHA is ignored, a new commit alone is nothing to deploy.
+func sameTags(deployed, tags map[string]string) bool {
+	count := 0
+	for key, value := range tags {
+		if key == "gozap:git-sha" {
+			continue
+		}
+		if deployed[key] != value {
+			return false
+		}
+		count++
+	}
+	for key := range deployed {
+		if key != "gozap:git-sha" {
+			count--
+		}
+	}
+	return count == 0
+}
+
+// readTagPolicy reads the org tag policy doctor enforces
+func readTagPolicy(file string) (*TagPolicy, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &TagPolicy{}
+	if err := json.Unmarshal(content, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return policy, nil
+}
+
+// checkTags validates the stage's tags and enforces the org tag policy. The
+// policy comes from --tag-policy, GOZAP_TAG_POLICY or tag-policy.json.
+func checkTags(opts *DoctorOptions) []doctorFinding {
+	if err := validateTags(opts.StageConfig.Tags); err != nil {
+		return []doctorFinding{{Warning: true, Message: err.Error()}}
+	}
+	tags, err := stackTags(opts.StageConfig)
+	if err != nil {
+		return []doctorFinding{{Warning: true, Message: err.Error()}}
+	}
+
+	file := opts.TagPolicy
+	if file == "" {
+		file = os.Getenv("GOZAP_TAG_POLICY")
+	}
+	explicit := file != ""
+	if !explicit {
+		file = tagPolicyFile
+	}
+
+	policy, err := readTagPolicy(file)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return []doctorFinding{{Message: fmt.Sprintf("%d stack tags, no tag policy found", len(tags))}}
+	}
+	if err != nil {
+		return []doctorFinding{{Warning: true, Message: fmt.Sprintf("failed to read tag policy: %v", err)}}
+	}
+
+	var findings []doctorFinding
+	for _, key := range policy.RequiredKeys {
+		if _, found := tags[key]; !found {
+			findings = append(findings, doctorFinding{Warning: true, Message: fmt.Sprintf("required tag '%s' is missing, add it to Tags in config.json or to %s", key, projectTagsFile)})
+		}
+	}
+	for _, key := range tagKeys(tags) {
+		allowed, restricted := policy.AllowedValues[key]
+		if restricted && !slices.Contains(allowed, tags[key]) {
+			findings = append(findings, doctorFinding{Warning: true, Message: fmt.Sprintf("tag '%s' is '%s', the policy allows %s", key, tags[key], strings.Join(allowed, ", "))})
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{Message: fmt.Sprintf("%d stack tags satisfy %s", len(tags), file)})
+	}
+	return findings
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSameTags(t *testing.T) {
+	tags := map[string]string{"team": "payments", "gozap:stage": "prod", "gozap:git-sha": "abc123"}
+
+	tests := []struct {
+		name     string
+		deployed map[string]string
+		want     bool
+	}{
+		{name: "identical", deployed: map[string]string{"team": "payments", "gozap:stage": "prod", "gozap:git-sha": "abc123"}, want: true},
+		{name: "other commit", deployed: map[string]string{"team": "payments", "gozap:stage": "prod", "gozap:git-sha": "def456"}, want: true},
+		{name: "no commit", deployed: map[string]string{"team": "payments", "gozap:stage": "prod"}, want: true},
+		{name: "changed value", deployed: map[string]string{"team": "billing", "gozap:stage": "prod", "gozap:git-sha": "abc123"}, want: false},
+		{name: "missing tag", deployed: map[string]string{"gozap:stage": "prod", "gozap:git-sha": "abc123"}, want: false},
+		{name: "extra tag", deployed: map[string]string{"team": "payments", "owner": "ops", "gozap:stage": "prod"}, want: false},
+		{name: "nothing deployed", deployed: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameTags(tt.deployed, tags); got != tt.want {
+				t.Errorf("sameTags(%v) = %v, want %v", tt.deployed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployTagArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want []string
+	}{
+		{name: "none", tags: nil, want: nil},
+		{name: "sorted", tags: map[string]string{"team": "payments", "env": "prod"}, want: []string{"env=prod", "team=payments"}},
+		{name: "separators in values", tags: map[string]string{"gozap:project": "app", "url": "https://a.example.com/?a=b"}, want: []string{
+			"gozap:project=app", "url=https://a.example.com/?a=b",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deployTagArgs(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deployTagArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTagArg(t *testing.T) {
+	tags := map[string]string{"team": "payments", "url": "https://a.example.com/?a=b"}
+	content, err := updateTagArg(tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []struct{ Key, Value string }
+	if err := json.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("updateTagArg() = %s, not JSON: %v", content, err)
+	}
+	if len(got) != 2 || got[0].Key != "team" || got[1].Key != "url" || got[1].Value != tags["url"] {
+		t.Errorf("updateTagArg() = %s", content)
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "plain", tags: map[string]string{"team": "payments", "cost-center": "4711"}},
+		{name: "allowed characters", tags: map[string]string{"app/owner": "ops@example.com", "note": "a b_c.d:e=f+g"}},
+		{name: "empty key", tags: map[string]string{"": "x"}, wantErr: true},
+		{name: "long key", tags: map[string]string{strings.Repeat("k", 129): "x"}, wantErr: true},
+		{name: "long value", tags: map[string]string{"k": strings.Repeat("v", 257)}, wantErr: true},
+		{name: "forbidden character", tags: map[string]string{"team": "payments!"}, wantErr: true},
+		{name: "aws prefix", tags: map[string]string{"aws:createdBy": "me"}, wantErr: true},
+		{name: "gozap prefix in any case", tags: map[string]string{"GoZap:stage": "prod"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTags(tt.tags); (err != nil) != tt.wantErr {
+				t.Errorf("validateTags() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageTags(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "tags", change: func(c *DeploymentConfig) { c.Tags = map[string]string{"team": "payments"} }},
+		{name: "reserved tag prefix", change: func(c *DeploymentConfig) { c.Tags = map[string]string{"gozap:stage": "x"} }, wantErr: "reserved prefix"},
+	})
+}
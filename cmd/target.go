@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	regionPattern      = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d$`)
+	roleArnPattern     = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+	sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// targetOverrides holds the --region and --profile flags, which take
+// precedence over the stage's Region and Profile
+var targetOverrides struct {
+	Region  string
+	Profile string
+}
+
+// awsTarget is the account and region the aws CLI runs against
+type awsTarget struct {
+	Region  string
+	Profile string
+	Role    string
+	env     []string // temporary credentials of the assumed role
+}
+
+// currentTarget is the target of the stage being worked on. Commands switch
+// it with useTarget before calling AWS; until then the ambient configuration
+// of the aws CLI is used, narrowed by the overrides.
+var currentTarget = &awsTarget{}
+
+// AddTargetFlags registers the --region and --profile overrides on the root
+// command so every command accepts them
+func AddTargetFlags(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&targetOverrides.Region, "region", "", "AWS region to use, overrides the stage's Region")
+	root.PersistentFlags().StringVar(&targetOverrides.Profile, "profile", "", "AWS CLI profile to use, overrides the stage's Profile")
+}
+
+// awsCommand prepares an aws CLI command against the current target
+func awsCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("aws", args...)
+	cmd.Env = currentTarget.environ()
+	return cmd
+}
+
+// environ returns the process environment with the target applied
+func (t *awsTarget) environ() []string {
+	region, profile := t.Region, t.Profile
+	if region == "" {
+		region = targetOverrides.Region
+	}
+	if profile == "" {
+		profile = targetOverrides.Profile
+	}
+
+	env := os.Environ()
+	if region != "" {
+		env = append(env, "AWS_REGION="+region, "AWS_DEFAULT_REGION="+region)
+	}
+	if len(t.env) > 0 {
+		// The role credentials must win over any profile
+		env = withoutEnv(env, "AWS_PROFILE", "AWS_DEFAULT_PROFILE")
+		return append(env, t.env...)
+	}
+	if profile != "" {
+		env = append(env, "AWS_PROFILE="+profile)
+	}
+	return env
+}
+
+func withoutEnv(env []string, names ...string) []string {
+	kept := env[:0:0]
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		found := false
+		for _, n := range names {
+			found = found || name == n
+		}
+		if !found {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// validateTarget checks the stage's Region, Profile and role settings
+func validateTarget(stageConfig DeploymentConfig) error {
+	if stageConfig.Region != "" && !regionPattern.MatchString(stageConfig.Region) {
+		return fmt.Errorf("❌ '%s' is not an AWS region", stageConfig.Region)
+	}
+	if stageConfig.AssumeRoleArn == "" {
+		if stageConfig.ExternalId != "" || stageConfig.SessionName != "" {
+			return fmt.Errorf("❌ ExternalId and SessionName require AssumeRoleArn")
+		}
+		return nil
+	}
+	if !roleArnPattern.MatchString(stageConfig.AssumeRoleArn) {
+		return fmt.Errorf("❌ '%s' is not an IAM role ARN", stageConfig.AssumeRoleArn)
+	}
+	if stageConfig.SessionName != "" && !sessionNamePattern.MatchString(stageConfig.SessionName) {
+		return fmt.Errorf("❌ SessionName must be 2 to 64 letters, numbers or +=,.@_- characters, got '%s'", stageConfig.SessionName)
+	}
+	return nil
+}
+
+// useTarget points the aws CLI at the stage's account and region and
+// switches the stage to a bucket of that region when needed
+func useTarget(stageConfig *DeploymentConfig) error {
+	if err := switchTarget(*stageConfig); err != nil {
+		return err
+	}
+	if currentTarget.Profile != "" || currentTarget.Role != "" || stageConfig.Region != "" || targetOverrides.Region != "" {
+		fmt.Printf("🌍 Targeting %s\n", currentTarget)
+	}
+	return resolveBucket(stageConfig)
+}
+
+// switchTarget points the aws CLI at the stage's account and region,
+// assuming its role when one is configured
+func switchTarget(stageConfig DeploymentConfig) error {
+	if err := validateTarget(stageConfig); err != nil {
+		return err
+	}
+
+	target := &awsTarget{Region: targetOverrides.Region, Profile: targetOverrides.Profile}
+	if target.Region == "" {
+		target.Region = stageConfig.Region
+	}
+	if target.Region == "" && len(stageConfig.Regions) > 0 {
+		if len(stageConfig.Regions) > 1 {
+			return fmt.Errorf("❌ stage '%s' is deployed to %s, pass --region to pick one", stageConfig.Stage, strings.Join(stageConfig.Regions, ", "))
+		}
+		target.Region = stageConfig.Regions[0]
+	}
+	if target.Profile == "" {
+		target.Profile = stageConfig.Profile
+	}
+	currentTarget = target
+	if target.Region == "" {
+		target.Region = defaultRegion()
+	}
+
+	if stageConfig.AssumeRoleArn != "" {
+		if err := target.assumeRole(stageConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *awsTarget) String() string {
+	parts := []string{t.Region}
+	if t.Region == "" {
+		parts[0] = "the default region"
+	}
+	if t.Profile != "" {
+		parts = append(parts, "profile "+t.Profile)
+	}
+	if t.Role != "" {
+		parts = append(parts, "role "+t.Role)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// assumeRole trades the profile's credentials for temporary credentials of
+// the stage's role
+func (t *awsTarget) assumeRole(stageConfig DeploymentConfig) error {
+	session := stageConfig.SessionName
+	if session == "" {
+		session = strings.TrimSuffix("gozap-"+stageConfig.Stage, "-")
+	}
+
+	args := []string{
+		"sts", "assume-role",
+		"--role-arn", stageConfig.AssumeRoleArn,
+		"--role-session-name", session,
+		"--output", "json",
+	}
+	if stageConfig.ExternalId != "" {
+		args = append(args, "--external-id", stageConfig.ExternalId)
+	}
+
+	output, err := awsCommand(args...).Output()
+	if err != nil {
+		return fmt.Errorf("❌ failed to assume role '%s': %w", stageConfig.AssumeRoleArn, err)
+	}
+
+	var response struct {
+		Credentials struct {
+			AccessKeyId     string `json:"AccessKeyId"`
+			SecretAccessKey string `json:"SecretAccessKey"`
+			SessionToken    string `json:"SessionToken"`
+		} `json:"Credentials"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return fmt.Errorf("failed to parse assumed role credentials: %w", err)
+	}
+
+	t.Role = stageConfig.AssumeRoleArn
+	t.env = []string{
+		"AWS_ACCESS_KEY_ID=" + response.Credentials.AccessKeyId,
+		"AWS_SECRET_ACCESS_KEY=" + response.Credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN=" + response.Credentials.SessionToken,
+	}
+	return nil
+}
+
+// sameTarget reports whether two targets reach the same account and region
+func sameTarget(a, b *awsTarget) bool {
+	return a.Region == b.Region && a.Profile == b.Profile && a.Role == b.Role
+}
+
+// bucketRegion returns the region a bucket lives in
+func bucketRegion(bucket string) (string, error) {
+	output, err := awsCommand("s3api", "get-bucket-location", "--bucket", bucket, "--query", "LocationConstraint", "--output", "text").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get location of bucket '%s': %w", bucket, err)
+	}
+
+	// Buckets in us-east-1 have no location constraint, and the oldest ones
+	// in eu-west-1 report the legacy 'EU'
+	switch region := strings.TrimSpace(string(output)); region {
+	case "", "None", "null":
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	default:
+		return region, nil
+	}
+}
+
+// checkStageBucket checks that the stage's bucket exists in the stage's
+// region before it is written to config.json
+func checkStageBucket(stageConfig DeploymentConfig) error {
+	if err := switchTarget(stageConfig); err != nil {
+		return err
+	}
+	if err := checkS3Bucket(stageConfig.S3Bucket); err != nil {
+		return err
+	}
+
+	location, err := bucketRegion(stageConfig.S3Bucket)
+	if err != nil || currentTarget.Region == "" || location == currentTarget.Region {
+		return nil
+	}
+	return fmt.Errorf("❌ bucket '%s' is in %s but the stage deploys to %s", stageConfig.S3Bucket, location, currentTarget.Region)
+}
+
+// resolveBucket makes sure the stage deploys through a bucket in the target
+// region, since Lambda only reads code from its own region. The region's
+// entry in Buckets, or else its bootstrapped bucket, replaces a bucket from
+// another region.
+func resolveBucket(stageConfig *DeploymentConfig) error {
+	region := currentTarget.Region
+	if region == "" {
+		return nil
+	}
+	if bucket, found := stageConfig.Buckets[region]; found {
+		stageConfig.S3Bucket = bucket
+	}
+
+	// A missing or unreadable bucket is reported by the bucket and lock
+	// checks that follow
+	location, err := bucketRegion(stageConfig.S3Bucket)
+	if err != nil {
+		return nil
+	}
+	if location == region {
+		return nil
+	}
+
+	if bucket := bootstrapBucket(region); bucket != "" {
+		fmt.Printf("Bucket '%s' is in %s, using the bootstrapped bucket '%s' of %s\n", stageConfig.S3Bucket, location, bucket, region)
+		stageConfig.S3Bucket = bucket
+		return nil
+	}
+	return fmt.Errorf("❌ bucket '%s' is in %s but the stage deploys to %s, run 'gozap bootstrap --region %s' or set S3Bucket to a bucket in %s",
+		stageConfig.S3Bucket, location, region, region, region)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeTargetAWS answers the bucket location, account and role lookups of the
+// target helpers
+const fakeTargetAWS = `case "$1 $2" in
+  "s3api get-bucket-location")
+    case "$4" in
+      us-bucket) echo None ;;
+      eu-bucket) echo EU ;;
+      tokyo-bucket) echo ap-northeast-1 ;;
+      *) exit 254 ;;
+    esac ;;
+  "sts get-caller-identity") echo 123456789012 ;;
+  "sts assume-role") echo '{"Credentials": {"AccessKeyId": "AKIA", "SecretAccessKey": "secret", "SessionToken": "token"}}' ;;
+  "configure get") echo us-west-2 ;;
+  *) exit 1 ;;
+esac
+`
+
+// useFakeTarget runs the test against fakeTargetAWS with no region or
+// profile from the environment, and restores the target afterwards
+func useFakeTarget(t *testing.T) {
+	t.Helper()
+	fakeAWS(t, fakeTargetAWS)
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	target, overrides := currentTarget, targetOverrides
+	t.Cleanup(func() {
+		currentTarget, targetOverrides = target, overrides
+	})
+}
+
+func TestValidateStageTarget(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "target", change: func(c *DeploymentConfig) {
+			c.Region = "eu-central-1"
+			c.Profile = "prod"
+			c.AssumeRoleArn = "arn:aws:iam::123456789012:role/deploy"
+			c.ExternalId = "x"
+			c.SessionName = "ci@example.com"
+		}},
+		{name: "region", change: func(c *DeploymentConfig) { c.Region = "Europe" }, wantErr: "not an AWS region"},
+		{name: "role arn", change: func(c *DeploymentConfig) { c.AssumeRoleArn = "arn:aws:iam::123:user/x" }, wantErr: "IAM role ARN"},
+		{name: "external id without role", change: func(c *DeploymentConfig) { c.ExternalId = "x" }, wantErr: "require AssumeRoleArn"},
+		{name: "session name", change: func(c *DeploymentConfig) {
+			c.AssumeRoleArn = "arn:aws:iam::123456789012:role/deploy"
+			c.SessionName = "deploy from ci"
+		}, wantErr: "SessionName"},
+	})
+}
+
+func TestSwitchTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides awsTarget
+		stage     DeploymentConfig
+		want      awsTarget
+		wantErr   bool
+	}{
+		{name: "ambient", want: awsTarget{Region: "us-west-2"}},
+		{name: "stage", stage: DeploymentConfig{Region: "eu-west-1", Profile: "prod"}, want: awsTarget{Region: "eu-west-1", Profile: "prod"}},
+		{name: "overrides", overrides: awsTarget{Region: "us-east-2", Profile: "ops"}, stage: DeploymentConfig{Region: "eu-west-1", Profile: "prod"}, want: awsTarget{Region: "us-east-2", Profile: "ops"}},
+		{name: "single region", stage: DeploymentConfig{Regions: []string{"ap-northeast-1"}}, want: awsTarget{Region: "ap-northeast-1"}},
+		{name: "several regions", stage: DeploymentConfig{Regions: []string{"us-east-1", "eu-west-1"}}, wantErr: true},
+		{name: "several regions with --region", overrides: awsTarget{Region: "eu-west-1"}, stage: DeploymentConfig{Regions: []string{"us-east-1", "eu-west-1"}}, want: awsTarget{Region: "eu-west-1"}},
+		{name: "invalid stage", stage: DeploymentConfig{Region: "Europe"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeTarget(t)
+			targetOverrides.Region, targetOverrides.Profile = tt.overrides.Region, tt.overrides.Profile
+
+			err := switchTarget(tt.stage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("switchTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !sameTarget(currentTarget, &tt.want) {
+				t.Errorf("target = %s, want %s", currentTarget, &tt.want)
+			}
+		})
+	}
+}
+
+func TestSwitchTargetAssumeRole(t *testing.T) {
+	useFakeTarget(t)
+	t.Setenv("AWS_PROFILE", "ambient")
+
+	stageConfig := DeploymentConfig{Stage: "prod", Region: "eu-west-1", AssumeRoleArn: "arn:aws:iam::123456789012:role/deploy"}
+	if err := switchTarget(stageConfig); err != nil {
+		t.Fatalf("switchTarget() error = %v", err)
+	}
+	if currentTarget.Role != stageConfig.AssumeRoleArn {
+		t.Errorf("target role = %q, want %q", currentTarget.Role, stageConfig.AssumeRoleArn)
+	}
+
+	env := currentTarget.environ()
+	for _, want := range []string{"AWS_REGION=eu-west-1", "AWS_ACCESS_KEY_ID=AKIA", "AWS_SESSION_TOKEN=token"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("environment lacks %s", want)
+		}
+	}
+	// The role's credentials must not be overridden by a profile
+	for _, entry := range env {
+		if strings.HasPrefix(entry, "AWS_PROFILE=") {
+			t.Errorf("environment keeps %s next to the role credentials", entry)
+		}
+	}
+}
+
+func TestResolveBucket(t *testing.T) {
+	tests := []struct {
+		name      string
+		region    string
+		stage     DeploymentConfig
+		bootstrap map[string]string // bootstrapped buckets by region
+		want      string
+		wantErr   bool
+	}{
+		{name: "bucket in the region", region: "ap-northeast-1", stage: DeploymentConfig{S3Bucket: "tokyo-bucket"}, want: "tokyo-bucket"},
+		{name: "us-east-1 has no location", region: "us-east-1", stage: DeploymentConfig{S3Bucket: "us-bucket"}, want: "us-bucket"},
+		{name: "legacy EU location", region: "eu-west-1", stage: DeploymentConfig{S3Bucket: "eu-bucket"}, want: "eu-bucket"},
+		{
+			name:   "regional bucket",
+			region: "ap-northeast-1",
+			stage:  DeploymentConfig{S3Bucket: "us-bucket", Buckets: map[string]string{"ap-northeast-1": "tokyo-bucket"}},
+			want:   "tokyo-bucket",
+		},
+		{
+			name:      "bootstrapped bucket",
+			region:    "ap-northeast-1",
+			stage:     DeploymentConfig{S3Bucket: "us-bucket"},
+			bootstrap: map[string]string{"ap-northeast-1": "tokyo-bucket"},
+			want:      "tokyo-bucket",
+		},
+		{name: "bucket in another region", region: "ap-northeast-1", stage: DeploymentConfig{S3Bucket: "us-bucket"}, wantErr: true},
+		{name: "unknown bucket is left to later checks", region: "ap-northeast-1", stage: DeploymentConfig{S3Bucket: "missing"}, want: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeTarget(t)
+			currentTarget = &awsTarget{Region: tt.region}
+			for region, bucket := range tt.bootstrap {
+				if err := recordBootstrapBucket(region, bucket); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := resolveBucket(&tt.stage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.stage.S3Bucket != tt.want {
+				t.Errorf("S3Bucket = %s, want %s", tt.stage.S3Bucket, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testSha256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+// renderedTemplate is a stage template rendered by generateTemplate and
+// parsed as YAML
+type renderedTemplate struct {
+	Parameters map[string]yaml.Node        `yaml:"Parameters"`
+	Conditions map[string]yaml.Node        `yaml:"Conditions"`
+	Resources  map[string]renderedResource `yaml:"Resources"`
+}
+
+type renderedResource struct {
+	Type       string    `yaml:"Type"`
+	DependsOn  []string  `yaml:"DependsOn"`
+	Properties yaml.Node `yaml:"Properties"`
+}
+
+// deployableStage returns a valid stage with the values set at deploy time
+func deployableStage() DeploymentConfig {
+	c := validStage()
+	c.S3Key = "deployment-" + testSha256 + ".zip"
+	c.CodeSha256 = testSha256
+	return c
+}
+
+// renderTemplate renders the stage's template and parses it, failing the
+// test unless it is valid YAML in which every resource has a Type
+func renderTemplate(t *testing.T, stageConfig DeploymentConfig) renderedTemplate {
+	t.Helper()
+	outFile := filepath.Join(t.TempDir(), "template.yaml")
+	if err := generateTemplate(outFile, &stageConfig); err != nil {
+		t.Fatalf("generateTemplate() error = %v", err)
+	}
+	content, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var template renderedTemplate
+	if err := yaml.Unmarshal(content, &template); err != nil {
+		t.Fatalf("template is not valid YAML: %v\n%s", err, content)
+	}
+	for name, resource := range template.Resources {
+		if resource.Type == "" {
+			t.Errorf("resource %s has no Type", name)
+		}
+	}
+	return template
+}
+
+// resource checks the type of a resource and decodes its properties into
+// properties unless it is nil
+func (r renderedTemplate) resource(t *testing.T, name, resourceType string, properties any) {
+	t.Helper()
+	resource, ok := r.Resources[name]
+	if !ok {
+		t.Fatalf("resource %s is missing", name)
+	}
+	if resource.Type != resourceType {
+		t.Fatalf("resource %s has Type %s, want %s", name, resource.Type, resourceType)
+	}
+	if properties != nil {
+		if err := resource.Properties.Decode(properties); err != nil {
+			t.Fatalf("resource %s has unexpected properties: %v", name, err)
+		}
+	}
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	template := renderTemplate(t, stageConfig)
+
+	for _, name := range []string{"AliasVersion", "CanaryWeight"} {
+		if _, ok := template.Parameters[name]; !ok {
+			t.Errorf("parameter %s is missing", name)
+		}
+	}
+	for _, name := range []string{"AliasPinned", "AliasRouted"} {
+		if _, ok := template.Conditions[name]; !ok {
+			t.Errorf("condition %s is missing", name)
+		}
+	}
+
+	var lambda struct {
+		Code struct {
+			S3Bucket string `yaml:"S3Bucket"`
+			S3Key    string `yaml:"S3Key"`
+		} `yaml:"Code"`
+		MemorySize int    `yaml:"MemorySize"`
+		Timeout    int    `yaml:"Timeout"`
+		Runtime    string `yaml:"Runtime"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if lambda.Code.S3Bucket != "bucket" || lambda.Code.S3Key != stageConfig.S3Key {
+		t.Errorf("Lambda code = %+v, want the stage's artifact", lambda.Code)
+	}
+	if lambda.MemorySize != 128 || lambda.Timeout != 30 || lambda.Runtime != "provided.al2" {
+		t.Errorf("Lambda = %+v", lambda)
+	}
+
+	var version struct {
+		CodeSha256  string `yaml:"CodeSha256"`
+		Description string `yaml:"Description"`
+	}
+	template.resource(t, "Version"+stageConfig.VersionID(), "AWS::Lambda::Version", &version)
+	if version.CodeSha256 != "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=" {
+		t.Errorf("Version CodeSha256 = %q, want the artifact hash in base64", version.CodeSha256)
+	}
+	if describedArtifact(version.Description) != testSha256 {
+		t.Errorf("Version Description = %q does not record the artifact", version.Description)
+	}
+
+	var alias struct {
+		FunctionVersion yaml.Node `yaml:"FunctionVersion"`
+		Name            string    `yaml:"Name"`
+		RoutingConfig   yaml.Node `yaml:"RoutingConfig"`
+	}
+	template.resource(t, "Alias", "AWS::Lambda::Alias", &alias)
+	if alias.Name != "dev" {
+		t.Errorf("Alias Name = %q, want the stage", alias.Name)
+	}
+	// The alias follows the new version unless the stack parameters pin it
+	if fn := alias.FunctionVersion; fn.Tag != "!If" || len(fn.Content) != 3 || fn.Content[0].Value != "AliasPinned" ||
+		!strings.Contains(fn.Content[2].Value, "Version"+stageConfig.VersionID()) {
+		t.Errorf("Alias FunctionVersion is not pinned through AliasVersion: %s %v", fn.Tag, fn.Content)
+	}
+	if alias.RoutingConfig.Tag != "!If" || len(alias.RoutingConfig.Content) != 3 || alias.RoutingConfig.Content[0].Value != "AliasRouted" {
+		t.Errorf("Alias RoutingConfig is not routed through CanaryWeight")
+	}
+
+	template.resource(t, "Deployment"+stageConfig.DeploymentID(), "AWS::ApiGateway::Deployment", nil)
+	template.resource(t, "Api", "AWS::ApiGateway::RestApi", nil)
+}
+
+func TestGenerateTemplateInvalidHash(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.CodeSha256 = "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="
+	outFile := filepath.Join(t.TempDir(), "template.yaml")
+	if err := generateTemplate(outFile, &stageConfig); err == nil {
+		t.Error("generateTemplate() rendered a stage whose CodeSha256 is not hex")
+	}
+}
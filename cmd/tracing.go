@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed templates/collector.yaml.tmpl
+var collectorFS embed.FS
+
+// defaultAdotLayer is the AWS Distro for OpenTelemetry collector layer,
+// published in every region by the ADOT account
+const defaultAdotLayer = "arn:aws:lambda:${AWS::Region}:901920570463:layer:aws-otel-collector-amd64-ver-0-102-1:1"
+
+// collectorConfigName is the collector config packaged next to bootstrap
+const collectorConfigName = "collector.yaml"
+
+// TracingConfig enables X-Ray tracing for the function and the API stage.
+// With Adot the ADOT collector layer is attached so the application can
+// export OpenTelemetry spans to X-Ray through it.
+type TracingConfig struct {
+	Adot         bool   `json:",omitempty"`
+	AdotLayerArn string `json:",omitempty"` // defaults to the collector layer of the stage's region
+}
+
+func (t *TracingConfig) validate(stageConfig DeploymentConfig) error {
+	if !t.Adot {
+		if t.AdotLayerArn != "" {
+			return fmt.Errorf("❌ Tracing AdotLayerArn requires Adot")
+		}
+		return nil
+	}
+	if stageConfig.IsImage() {
+		return fmt.Errorf("❌ container images cannot use the ADOT layer, add the collector to the image instead")
+	}
+	if t.AdotLayerArn != "" && (!strings.HasPrefix(t.AdotLayerArn, "arn:") || !strings.Contains(t.AdotLayerArn, ":layer:")) {
+		return fmt.Errorf("❌ '%s' is not a layer version ARN", t.AdotLayerArn)
+	}
+	if len(stageConfig.Layers) > 4 {
+		return fmt.Errorf("❌ the ADOT layer leaves room for 4 more layers, got %d", len(stageConfig.Layers))
+	}
+	return nil
+}
+
+// AdotLayer returns the collector layer, rendered with Fn::Sub
+func (t *TracingConfig) AdotLayer() string {
+	if t.AdotLayerArn != "" {
+		return t.AdotLayerArn
+	}
+	return defaultAdotLayer
+}
+
+// EnvironmentVariables returns the variables the stage's features pass to
+// the function, sorted by the template
+func (c DeploymentConfig) EnvironmentVariables() map[string]string {
+	env := map[string]string{}
+	if c.Cors != nil {
+		env["GOZAP_CORS_ORIGINS"] = c.Cors.OriginsEnv()
+		if c.Cors.AllowCredentials {
+			env["GOZAP_CORS_CREDENTIALS"] = "true"
+		}
+	}
+	// Scaffolded applications export OpenTelemetry spans when set to "otel"
+	if c.Tracing != nil && c.Tracing.Adot {
+		env["GOZAP_TRACING"] = "otel"
+		env["OPENTELEMETRY_COLLECTOR_CONFIG_URI"] = "/var/task/" + collectorConfigName
+	} else if c.Tracing != nil {
+		env["GOZAP_TRACING"] = "xray"
+	}
+	return env
+}
+
+// collectorEntries writes the ADOT collector config into binDir and returns
+// it as a package entry, or nothing when the stage does not use ADOT
+func collectorEntries(binDir string, stageConfig DeploymentConfig) ([]zipEntry, error) {
+	if stageConfig.Tracing == nil || !stageConfig.Tracing.Adot {
+		return nil, nil
+	}
+
+	content, err := collectorFS.ReadFile("templates/collector.yaml.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collector config template: %w", err)
+	}
+
+	path := filepath.Join(binDir, collectorConfigName)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write collector config: %w", err)
+	}
+	return []zipEntry{{Source: path, Name: collectorConfigName}}, nil
+}
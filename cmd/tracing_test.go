@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestValidateStageTracing(t *testing.T) {
+	layers := func(n int) []string {
+		var arns []string
+		for range n {
+			arns = append(arns, "arn:aws:lambda:us-east-1:123456789012:layer:x:1")
+		}
+		return arns
+	}
+
+	runStageCases(t, []stageCase{
+		{name: "xray", change: func(c *DeploymentConfig) { c.Tracing = &TracingConfig{} }},
+		{name: "adot", change: func(c *DeploymentConfig) {
+			c.Tracing = &TracingConfig{Adot: true}
+			c.Layers = layers(4)
+		}},
+		{name: "tracing layer without adot", change: func(c *DeploymentConfig) {
+			c.Tracing = &TracingConfig{AdotLayerArn: "arn:aws:lambda:us-east-1:123456789012:layer:x:1"}
+		}, wantErr: "requires Adot"},
+		{name: "adot layer arn", change: func(c *DeploymentConfig) { c.Tracing = &TracingConfig{Adot: true, AdotLayerArn: "collector"} }, wantErr: "layer version ARN"},
+		{name: "adot image", change: func(c *DeploymentConfig) {
+			c.Package = "image"
+			c.Tracing = &TracingConfig{Adot: true}
+		}, wantErr: "ADOT layer"},
+		{name: "adot with five layers", change: func(c *DeploymentConfig) {
+			c.Tracing = &TracingConfig{Adot: true}
+			c.Layers = layers(5)
+		}, wantErr: "layers"},
+	})
+}
+
+func TestTracingEnvironmentVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracing *TracingConfig
+		want    map[string]string
+	}{
+		{name: "off", want: map[string]string{}},
+		{name: "xray", tracing: &TracingConfig{}, want: map[string]string{"GOZAP_TRACING": "xray"}},
+		{name: "adot", tracing: &TracingConfig{Adot: true}, want: map[string]string{
+			"GOZAP_TRACING":                      "otel",
+			"OPENTELEMETRY_COLLECTOR_CONFIG_URI": "/var/task/collector.yaml",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageConfig := validStage()
+			stageConfig.Tracing = tt.tracing
+			if got := stageConfig.EnvironmentVariables(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnvironmentVariables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorEntries(t *testing.T) {
+	binDir := t.TempDir()
+	stageConfig := validStage()
+
+	if entries, err := collectorEntries(binDir, stageConfig); err != nil || entries != nil {
+		t.Errorf("collectorEntries() without tracing = %v, %v, want nothing", entries, err)
+	}
+
+	stageConfig.Tracing = &TracingConfig{Adot: true}
+	entries, err := collectorEntries(binDir, stageConfig)
+	if err != nil {
+		t.Fatalf("collectorEntries() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != collectorConfigName {
+		t.Fatalf("collectorEntries() = %v, want the collector config", entries)
+	}
+	if content, err := os.ReadFile(entries[0].Source); err != nil || len(content) == 0 {
+		t.Errorf("collector config was not written: %v", err)
+	}
+}
+
+func TestTracingTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:x:1"}
+	stageConfig.Tracing = &TracingConfig{Adot: true}
+	template := renderTemplate(t, stageConfig)
+
+	var lambda struct {
+		Layers        []string `yaml:"Layers"`
+		TracingConfig struct {
+			Mode string `yaml:"Mode"`
+		} `yaml:"TracingConfig"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if want := []string{stageConfig.Layers[0], defaultAdotLayer}; !reflect.DeepEqual(lambda.Layers, want) {
+		t.Errorf("Lambda Layers = %v, want %v", lambda.Layers, want)
+	}
+	if lambda.TracingConfig.Mode != "Active" {
+		t.Errorf("Lambda TracingConfig Mode = %q, want Active", lambda.TracingConfig.Mode)
+	}
+
+	var role struct {
+		ManagedPolicyArns []string `yaml:"ManagedPolicyArns"`
+	}
+	template.resource(t, "Role", "AWS::IAM::Role", &role)
+	if !slices.Contains(role.ManagedPolicyArns, "arn:aws:iam::aws:policy/AWSXRayDaemonWriteAccess") {
+		t.Errorf("Role ManagedPolicyArns = %v, want X-Ray write access", role.ManagedPolicyArns)
+	}
+
+	var deployment struct {
+		StageDescription struct {
+			TracingEnabled bool `yaml:"TracingEnabled"`
+		} `yaml:"StageDescription"`
+	}
+	template.resource(t, "Deployment"+stageConfig.DeploymentID(), "AWS::ApiGateway::Deployment", &deployment)
+	if !deployment.StageDescription.TracingEnabled {
+		t.Error("API stage tracing is not enabled")
+	}
+}
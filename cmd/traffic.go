@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionID identifies what a published Lambda version captures: the code
+// and the function configuration. It is used in the version's logical ID so
+// that CloudFormation publishes a new version, and a traffic shift has
+// something to shift to, only when one of them changes. Events, tags,
+// alarms, WAF rules and usage plans live outside the version.
+func (c DeploymentConfig) VersionID() string {
+	version := struct {
+		CodeSha256          string
+		Package             string
+		Environment         map[string]string
+		Memory              int
+		Timeout             int
+		Layers              []string
+		Tracing             *TracingConfig
+		Vpc                 *VpcConfig
+		ReservedConcurrency *int
+	}{c.CodeSha256, c.Package, c.EnvironmentVariables(), c.Memory, c.Timeout, c.Layers, c.Tracing, c.Vpc, c.ReservedConcurrency}
+	return hashID(version)
+}
+
+// DeploymentID identifies the API methods and stage settings. It is used in
+// the API deployment's logical ID so that API Gateway deploys the stage again
+// when they change. Methods integrate with the alias, so new function
+// versions need no new deployment.
+func (c DeploymentConfig) DeploymentID() string {
+	deployment := struct {
+		Stage    string
+		Methods  []apiMethod
+		Cors     *CorsConfig
+		Auth     *AuthConfig
+		Throttle *ThrottleConfig
+		Tracing  bool
+	}{c.Stage, c.ApiMethods(), c.Cors, c.Auth, c.Throttle, c.Tracing != nil}
+	return hashID(deployment)
+}
+
+func hashID(v any) string {
+	content, _ := json.Marshal(v)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// parseTrafficShift parses the --canary ("10%") and --linear ("10%every1m")
+// flag values. It returns nil when neither is set.
+func parseTrafficShift(canary, linear string) (*TrafficShift, error) {
+	if canary != "" && linear != "" {
+		return nil, fmt.Errorf("❌ --canary and --linear cannot be used together")
+	}
+
+	if canary != "" {
+		percent, err := parsePercent(canary)
+		if err != nil {
+			return nil, fmt.Errorf("❌ invalid --canary value '%s': %w", canary, err)
+		}
+		return &TrafficShift{Percent: percent}, nil
+	}
+
+	if linear != "" {
+		step, every, found := strings.Cut(linear, "every")
+		if !found {
+			return nil, fmt.Errorf("❌ invalid --linear value '%s': expected format like 10%%every1m", linear)
+		}
+		percent, err := parsePercent(step)
+		if err != nil {
+			return nil, fmt.Errorf("❌ invalid --linear value '%s': %w", linear, err)
+		}
+		interval, err := time.ParseDuration(every)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("❌ invalid --linear interval '%s'", every)
+		}
+		return &TrafficShift{Percent: percent, Interval: interval}, nil
+	}
+
+	return nil, nil
+}
+
+func parsePercent(value string) (int, error) {
+	percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil {
+		return 0, fmt.Errorf("percentage must be a whole number")
+	}
+	if percent <= 0 || percent >= 100 {
+		return 0, fmt.Errorf("percentage must be between 1 and 99")
+	}
+	return percent, nil
+}
+
+type aliasInfo struct {
+	FunctionVersion string `json:"FunctionVersion"`
+	RoutingConfig   struct {
+		AdditionalVersionWeights map[string]float64 `json:"AdditionalVersionWeights"`
+	} `json:"RoutingConfig"`
+}
+
+// pendingVersion returns the version currently receiving shifted traffic, if any
+func (a *aliasInfo) pendingVersion() (string, float64) {
+	for version, weight := range a.RoutingConfig.AdditionalVersionWeights {
+		return version, weight
+	}
+	return "", 0
+}
+
+// publishesVersion reports whether the stack already publishes the Lambda
+// version with the given ID
+func publishesVersion(stackName, versionID string) bool {
+	cmd := awsCommand("cloudformation", "describe-stack-resource", "--stack-name", stackName, "--logical-resource-id", "Version"+versionID)
+	return cmd.Run() == nil
+}
+
+func getAlias(functionName, alias string) (*aliasInfo, error) {
+	cmd := awsCommand("lambda", "get-alias", "--function-name", functionName, "--name", alias)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias '%s': %w\n%s", alias, err, output)
+	}
+
+	var info aliasInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse alias details: %w", err)
+	}
+	return &info, nil
+}
+
+// aliasRouting is where the stage alias sends traffic. It is passed to the
+// stack as the AliasVersion and CanaryWeight parameters, so CloudFormation
+// stays the only owner of the alias and traffic shifts cause no drift.
+type aliasRouting struct {
+	Version string  // version the alias keeps, empty for the stack's version
+	Weight  float64 // share of traffic (0-1) routed to the stack's version
+}
+
+// parameters formats the routing for 'aws cloudformation update-stack
+// --parameters'. JSON keeps the empty AliasVersion intact.
+func (r aliasRouting) parameters() (string, error) {
+	content, err := json.Marshal([]map[string]string{
+		{"ParameterKey": "AliasVersion", "ParameterValue": r.Version},
+		{"ParameterKey": "CanaryWeight", "ParameterValue": strconv.FormatFloat(r.Weight, 'f', -1, 64)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stack parameters: %w", err)
+	}
+	return string(content), nil
+}
+
+// aliasRouting returns the routing the stack was last deployed with
+func (s *stackDescription) aliasRouting() aliasRouting {
+	var routing aliasRouting
+	for _, parameter := range s.Parameters {
+		switch parameter.ParameterKey {
+		case "AliasVersion":
+			routing.Version = parameter.ParameterValue
+		case "CanaryWeight":
+			routing.Weight, _ = strconv.ParseFloat(parameter.ParameterValue, 64)
+		}
+	}
+	return routing
+}
+
+// setStackRouting changes the alias routing through the stack, keeping its
+// template, tags and versions
+func setStackRouting(stackName string, routing aliasRouting) error {
+	parameters, err := routing.parameters()
+	if err != nil {
+		return err
+	}
+
+	cmd := awsCommand(
+		"cloudformation", "update-stack",
+		"--stack-name", stackName,
+		"--use-previous-template",
+		"--capabilities", "CAPABILITY_NAMED_IAM",
+		"--parameters", parameters,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update the alias routing of stack '%s': %w\n%s", stackName, err, output)
+	}
+	return waitForStackUpdate(stackName)
+}
+
+// runLinearShift moves traffic to the pending version in equal steps until it
+// receives all of it, stopping if the deploy lock is lost between steps
+func runLinearShift(functionName, alias string, shift *TrafficShift, lock *deployLock) error {
+	info, err := getAlias(functionName, alias)
+	if err != nil {
+		return err
+	}
+
+	pending, _ := info.pendingVersion()
+	if pending == "" {
+		return fmt.Errorf("no traffic shift in progress for alias '%s'", alias)
+	}
+
+	for percent := shift.Percent + shift.Percent; percent < 100; percent += shift.Percent {
+		fmt.Printf("Waiting %s before the next traffic shift...\n", shift.Interval)
+		time.Sleep(shift.Interval)
+		if err := lock.Check(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Shifting %d%% of traffic to version %s...\n", percent, pending)
+		if err := setStackRouting(functionName, aliasRouting{Version: info.FunctionVersion, Weight: float64(percent) / 100}); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Waiting %s before completing the traffic shift...\n", shift.Interval)
+	time.Sleep(shift.Interval)
+	if err := lock.Check(); err != nil {
+		return err
+	}
+	return promoteAlias(functionName, alias)
+}
+
+// promoteAlias sends all traffic to the pending version. The function shares
+// its name with the stack.
+func promoteAlias(functionName, alias string) error {
+	info, err := getAlias(functionName, alias)
+	if err != nil {
+		return err
+	}
+
+	pending, _ := info.pendingVersion()
+	if pending == "" {
+		return fmt.Errorf("❌ no traffic shift in progress for alias '%s'", alias)
+	}
+
+	fmt.Printf("Shifting all traffic to version %s...\n", pending)
+	return setStackRouting(functionName, aliasRouting{})
+}
+
+// abortAlias sends all traffic back to the previous version
+func abortAlias(functionName, alias string) error {
+	info, err := getAlias(functionName, alias)
+	if err != nil {
+		return err
+	}
+
+	pending, _ := info.pendingVersion()
+	if pending == "" {
+		return fmt.Errorf("❌ no traffic shift in progress for alias '%s'", alias)
+	}
+
+	fmt.Printf("Reverting all traffic to version %s...\n", info.FunctionVersion)
+	return setStackRouting(functionName, aliasRouting{Version: info.FunctionVersion})
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTrafficShift(t *testing.T) {
+	tests := []struct {
+		name    string
+		canary  string
+		linear  string
+		want    *TrafficShift
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "canary", canary: "10%", want: &TrafficShift{Percent: 10}},
+		{name: "canary without percent sign", canary: "25", want: &TrafficShift{Percent: 25}},
+		{name: "linear", linear: "10%every1m", want: &TrafficShift{Percent: 10, Interval: time.Minute}},
+		{name: "linear in seconds", linear: "20every30s", want: &TrafficShift{Percent: 20, Interval: 30 * time.Second}},
+		{name: "both", canary: "10%", linear: "10%every1m", wantErr: true},
+		{name: "canary out of range", canary: "100%", wantErr: true},
+		{name: "linear without interval", linear: "10%", wantErr: true},
+		{name: "linear with bad interval", linear: "10%everysoon", wantErr: true},
+		{name: "linear with negative interval", linear: "10%every-1m", wantErr: true},
+		{name: "linear with bad percent", linear: "ten%every1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTrafficShift(tt.canary, tt.linear)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTrafficShift(%q, %q) error = %v, wantErr %v", tt.canary, tt.linear, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseTrafficShift(%q, %q) = %+v, want %+v", tt.canary, tt.linear, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "1%", want: 1},
+		{value: "50%", want: 50},
+		{value: "99", want: 99},
+		{value: "0%", wantErr: true},
+		{value: "100%", wantErr: true},
+		{value: "-5%", wantErr: true},
+		{value: "12.5%", wantErr: true},
+		{value: "%", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parsePercent(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePercent(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePercent(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionID(t *testing.T) {
+	base := DeploymentConfig{FunctionName: "app-dev", Stage: "dev", Memory: 128, Timeout: 30, CodeSha256: "abc"}
+	id := base.VersionID()
+	if len(id) != 12 {
+		t.Fatalf("VersionID() = %q, want 12 characters", id)
+	}
+	if base.VersionID() != id {
+		t.Errorf("VersionID() is not stable")
+	}
+
+	reserved := 5
+	tests := []struct {
+		name   string
+		change func(c *DeploymentConfig)
+		same   bool
+	}{
+		{name: "code", change: func(c *DeploymentConfig) { c.CodeSha256 = "def" }},
+		{name: "memory", change: func(c *DeploymentConfig) { c.Memory = 256 }},
+		{name: "timeout", change: func(c *DeploymentConfig) { c.Timeout = 60 }},
+		{name: "layers", change: func(c *DeploymentConfig) { c.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:x:1"} }},
+		{name: "environment", change: func(c *DeploymentConfig) { c.Cors = &CorsConfig{AllowOrigins: []string{"*"}} }},
+		{name: "tracing", change: func(c *DeploymentConfig) { c.Tracing = &TracingConfig{} }},
+		{name: "vpc", change: func(c *DeploymentConfig) {
+			c.Vpc = &VpcConfig{SubnetIds: []string{"subnet-1"}, SecurityGroupIds: []string{"sg-1"}}
+		}},
+		{name: "reserved concurrency", change: func(c *DeploymentConfig) { c.ReservedConcurrency = &reserved }},
+		{name: "schedules", change: func(c *DeploymentConfig) {
+			c.Schedules = []Schedule{{Expression: "rate(1 hour)", Method: "GET", Path: "/"}}
+		}, same: true},
+		{name: "tags", change: func(c *DeploymentConfig) { c.Tags = map[string]string{"team": "payments"} }, same: true},
+		{name: "monitoring", change: func(c *DeploymentConfig) { c.Monitoring = &MonitoringConfig{Emails: []string{"a@example.com"}} }, same: true},
+		{name: "waf", change: func(c *DeploymentConfig) { c.Waf = &WafConfig{RateLimit: 100} }, same: true},
+		{name: "usage plans", change: func(c *DeploymentConfig) { c.UsagePlans = []UsagePlan{{Name: "partners"}} }, same: true},
+		{name: "provisioned concurrency", change: func(c *DeploymentConfig) { c.ProvisionedConcurrency = 2 }, same: true},
+		{name: "image uri", change: func(c *DeploymentConfig) { c.ImageUri = "repo:tag" }, same: true},
+		{name: "authorizer hash", change: func(c *DeploymentConfig) { c.AuthorizerCodeSha256 = "fff" }, same: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			if got := changed.VersionID() == id; got != tt.same {
+				t.Errorf("VersionID() unchanged = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestDeploymentID(t *testing.T) {
+	base := DeploymentConfig{FunctionName: "app-dev", Stage: "dev", Memory: 128, Timeout: 30, CodeSha256: "abc"}
+	id := base.DeploymentID()
+
+	tests := []struct {
+		name   string
+		change func(c *DeploymentConfig)
+		same   bool
+	}{
+		{name: "auth", change: func(c *DeploymentConfig) { c.Auth = &AuthConfig{Type: "iam"} }},
+		{name: "cors", change: func(c *DeploymentConfig) { c.Cors = &CorsConfig{AllowOrigins: []string{"*"}} }},
+		{name: "throttle", change: func(c *DeploymentConfig) { c.Throttle = &ThrottleConfig{RateLimit: 10, BurstLimit: 20} }},
+		{name: "api keys", change: func(c *DeploymentConfig) { c.UsagePlans = []UsagePlan{{Name: "partners"}} }},
+		{name: "tracing", change: func(c *DeploymentConfig) { c.Tracing = &TracingConfig{} }},
+		{name: "code", change: func(c *DeploymentConfig) { c.CodeSha256 = "def" }, same: true},
+		{name: "memory", change: func(c *DeploymentConfig) { c.Memory = 256 }, same: true},
+		{name: "tags", change: func(c *DeploymentConfig) { c.Tags = map[string]string{"team": "payments"} }, same: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			if got := changed.DeploymentID() == id; got != tt.same {
+				t.Errorf("DeploymentID() unchanged = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestAliasRoutingParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		routing aliasRouting
+		want    map[string]string
+	}{
+		{name: "stack version", routing: aliasRouting{}, want: map[string]string{"AliasVersion": "", "CanaryWeight": "0"}},
+		{name: "canary", routing: aliasRouting{Version: "7", Weight: 0.1}, want: map[string]string{"AliasVersion": "7", "CanaryWeight": "0.1"}},
+		{name: "aborted", routing: aliasRouting{Version: "7"}, want: map[string]string{"AliasVersion": "7", "CanaryWeight": "0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := tt.routing.parameters()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The parameters round-trip through the stack description
+			stack := &stackDescription{}
+			if err := json.Unmarshal([]byte(content), &stack.Parameters); err != nil {
+				t.Fatalf("parameters() = %s, not JSON: %v", content, err)
+			}
+
+			got := map[string]string{}
+			for _, parameter := range stack.Parameters {
+				got[parameter.ParameterKey] = parameter.ParameterValue
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parameters() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("parameter %s = %q, want %q", key, got[key], value)
+				}
+			}
+			if stack.aliasRouting() != tt.routing {
+				t.Errorf("aliasRouting() = %+v, want %+v", stack.aliasRouting(), tt.routing)
+			}
+		})
+	}
+}
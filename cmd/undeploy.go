@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -29,32 +29,41 @@ func NewUndeployCommand() *cobra.Command {
 func runUndeploy(opts *UndeployOptions) error {
 	fmt.Printf("🗑️  Undeploying GoZap application for stage: %s\n", opts.Stage)
 
-	// 1. Read config file
-	config, err := readConfig("config.json")
+	// 1. Read the stage from config.json
+	stageConfig, stackName, err := loadStage(opts.Stage)
 	if err != nil {
 		return err
 	}
 
-	// 2. Validate stage exists in config
-	stageConfig, exists := config[opts.Stage]
-	if !exists {
-		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.Stage)
+	// 2. Find the regions the stack is deployed in. A stage with several
+	// Regions is undeployed from all of them unless --region picks one.
+	var deployed []DeploymentConfig
+	for _, regional := range regionalConfigs(stageConfig) {
+		if err := useTarget(&regional); err != nil {
+			return err
+		}
+		if err := checkStackExists(stackName); err != nil {
+			fmt.Printf("Stack '%s' does not exist in %s\n", stackName, currentTarget)
+			continue
+		}
+		deployed = append(deployed, regional)
 	}
-
-	// 3. Determine stack name
-	stackName := fmt.Sprintf("%s-%s", stageConfig.FunctionName, opts.Stage)
-
-	// 4. Check if the CloudFormation stack exists
-	fmt.Printf("Checking if stack '%s' exists...\n", stackName)
-	if err := checkStackExists(stackName); err != nil {
+	if len(deployed) == 0 {
 		return fmt.Errorf("❌ Stack '%s' does not exist or cannot be accessed", stackName)
 	}
 
-	// 5. Confirmation prompt (unless --force is used)
+	// 3. Confirmation prompt (unless --force is used)
 	if !opts.Force {
 		fmt.Printf("\n⚠️  WARNING: This will permanently delete the following resources:\n")
 		fmt.Printf("  - Lambda function: %s\n", stageConfig.FunctionName)
 		fmt.Printf("  - CloudFormation stack: %s\n", stackName)
+		if len(deployed) > 1 {
+			regions := make([]string, len(deployed))
+			for i, regional := range deployed {
+				regions[i] = regional.Region
+			}
+			fmt.Printf("  - In regions: %s\n", strings.Join(regions, ", "))
+		}
 		fmt.Printf("  - All associated AWS resources\n\n")
 
 		if !confirmAction("Are you sure you want to proceed with undeployment?") {
@@ -63,27 +72,49 @@ func runUndeploy(opts *UndeployOptions) error {
 		}
 	}
 
-	// 6. Delete the CloudFormation stack
-	if err := deleteStack(stackName); err != nil {
+	// 4. Delete the stack of every region
+	for _, regional := range deployed {
+		if err := undeployRegion(regional, stackName); err != nil {
+			return err
+		}
+	}
+
+	// 5. Success message
+	fmt.Printf("✅ Successfully undeployed application from stage '%s'\n", opts.Stage)
+	fmt.Println("💡 The configuration in config.json has been preserved for future deployments")
+
+	return nil
+}
+
+// undeployRegion deletes the stage's stack in the stage's region under the
+// stage's deploy lock. The stage's bucket was already resolved for the region.
+func undeployRegion(stageConfig DeploymentConfig, stackName string) error {
+	if err := switchTarget(stageConfig); err != nil {
 		return err
 	}
 
-	// 7. Wait for stack deletion to complete
-	if err := waitForStackDeletion(stackName); err != nil {
+	// Acquire the deploy lock for the stage
+	lock, err := acquireLock(stageConfig.S3Bucket, stackName, "undeploy")
+	if err != nil {
 		return err
 	}
+	defer lock.Release()
 
-	// 8. Success message
-	fmt.Printf("✅ Successfully undeployed application from stage '%s'\n", opts.Stage)
-	fmt.Println("💡 The configuration in config.json has been preserved for future deployments")
+	if err := deleteStack(stackName); err != nil {
+		return err
+	}
+	if err := waitForStackDeletion(stackName); err != nil {
+		return err
+	}
 
+	recordHistory("undeploy", stageConfig, stackName)
 	return nil
 }
 
 func deleteStack(stackName string) error {
 	fmt.Printf("Deleting CloudFormation stack '%s'...\n", stackName)
 
-	deleteCmd := exec.Command("aws", "cloudformation", "delete-stack", "--stack-name", stackName)
+	deleteCmd := awsCommand("cloudformation", "delete-stack", "--stack-name", stackName)
 	if output, err := deleteCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to delete CloudFormation stack: %w\n%s", err, output)
 	}
@@ -95,8 +126,8 @@ func deleteStack(stackName string) error {
 func waitForStackDeletion(stackName string) error {
 	fmt.Printf("Waiting for stack '%s' deletion to complete...\n", stackName)
 
-	waitCmd := exec.Command(
-		"aws", "cloudformation", "wait", "stack-delete-complete",
+	waitCmd := awsCommand(
+		"cloudformation", "wait", "stack-delete-complete",
 		"--stack-name", stackName,
 	)
 
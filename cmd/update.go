@@ -3,11 +3,11 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -27,14 +27,36 @@ func NewUpdateCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&opts.Stage, "stage", "s", "", "Stage of the project (e.g., dev, prod)")
+	cmd.Flags().StringVar(&opts.Canary, "canary", "", "Shift this share of traffic to the new version and wait for promote/abort (e.g., 10%)")
+	cmd.Flags().StringVar(&opts.Linear, "linear", "", "Shift traffic to the new version in equal steps (e.g., 10%every1m)")
+	cmd.Flags().IntVar(&opts.MultiRegion.Parallel, "parallel", 3, "Regions to update at the same time when the stage lists several Regions")
+	cmd.Flags().BoolVar(&opts.MultiRegion.ContinueOnError, "continue-on-error", false, "Keep updating the remaining regions after a region failed")
 	cmd.MarkFlagRequired("stage")
 
 	return cmd
 }
 
+// trafficShiftArgs passes the traffic shift flags on to the update of each
+// region of a multi-region stage
+func trafficShiftArgs(opts *UpdateOptions) []string {
+	var args []string
+	if opts.Canary != "" {
+		args = append(args, "--canary", opts.Canary)
+	}
+	if opts.Linear != "" {
+		args = append(args, "--linear", opts.Linear)
+	}
+	return args
+}
+
 func runUpdate(opts *UpdateOptions) error {
 	fmt.Println("🔄 Updating GoZap project...")
 
+	shift, err := parseTrafficShift(opts.Canary, opts.Linear)
+	if err != nil {
+		return err
+	}
+
 	// 1. Read config file
 	config, err := readConfig("config.json")
 	if err != nil {
@@ -46,15 +68,17 @@ func runUpdate(opts *UpdateOptions) error {
 	if !exists {
 		return fmt.Errorf("❌ stage '%s' not found in configuration", opts.Stage)
 	}
+	stageConfig.Stage = opts.Stage
 
-	// Set the deployment file name
-	currentTime := time.Now().Format("20060102150405")
-	zipFileName := fmt.Sprintf("deployment-%s.zip", currentTime)
+	// A stage with several Regions is deployed to each of them
+	if fansOut(stageConfig) {
+		return runMultiRegion("update", stageConfig, opts.MultiRegion, trafficShiftArgs(opts))
+	}
 
-	// Update config with new S3Key
-	stageConfig.S3Key = zipFileName
-	stageConfig.Stage = opts.Stage
-	config[opts.Stage] = stageConfig
+	// Point the aws CLI at the stage's account and region
+	if err := useTarget(&stageConfig); err != nil {
+		return err
+	}
 
 	// 2. Check if the CloudFormation stack exists
 	stackName := fmt.Sprintf("%s-%s", stageConfig.FunctionName, opts.Stage)
@@ -62,43 +86,98 @@ func runUpdate(opts *UpdateOptions) error {
 		return err
 	}
 
-	// Create temporary directories
+	// Acquire the deploy lock for the stage
+	lock, err := acquireLock(stageConfig.S3Bucket, stackName, "update")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// Create temporary directories. Regions deployed together share the
+	// prebuilt package and work in their own directory.
+	prebuilt, err := prebuiltDir(stageConfig)
+	if err != nil {
+		return err
+	}
 	tempDir := "bin"
+	templateFile := "template.yaml"
+	if prebuilt != "" {
+		tempDir = filepath.Join(prebuilt, currentTarget.Region)
+		templateFile = filepath.Join(tempDir, "template.yaml")
+	}
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	// Setup cleanup for local files only (not S3 yet)
-	defer cleanupFiles([]string{zipFileName, "template.yaml", tempDir})
+	// Setup cleanup for local files
+	defer cleanupFiles([]string{templateFile, tempDir})
 
-	// 3. Build the project
-	if err := buildProject(tempDir); err != nil {
+	// 3. Build and package the project
+	artifact, authorizer, err := packageStage(tempDir, &stageConfig, prebuilt)
+	if err != nil {
 		return err
 	}
 
-	// 4. Zip the project
-	if err := zipProject(zipFileName, filepath.Join(tempDir, "bootstrap")); err != nil {
-		return err
+	// Update config with the content-addressed S3Key
+	stageConfig.S3Key = artifact.Key()
+	stageConfig.CodeSha256 = artifact.Sha256
+	if stageConfig.IsImage() {
+		if err := resolveImageUri(&stageConfig, artifact); err != nil {
+			return err
+		}
 	}
 
-	// 5. Upload to S3
-	if err := uploadToS3(zipFileName, stageConfig.S3Bucket, zipFileName); err != nil {
+	// 4. Generate CloudFormation template
+	if err := generateTemplate(templateFile, &stageConfig); err != nil {
 		return err
 	}
+	if err := checkEventBuckets(stackName, stageConfig); err != nil {
+		return err
+	}
+
+	// Keep the alias on the current version and route part of the traffic
+	// to the new one when shifting gradually. Without a new version there is
+	// nothing to shift to.
+	if shift != nil && publishesVersion(stackName, stageConfig.VersionID()) {
+		fmt.Println("⚠️  Function code and configuration are unchanged, no new version to shift traffic to")
+		shift = nil
+	}
+	routing := aliasRouting{}
+	if shift != nil {
+		alias, err := getAlias(stackName, opts.Stage)
+		if err != nil {
+			return fmt.Errorf("❌ traffic shifting requires an existing '%s' alias, run 'update' without --canary/--linear first: %w", opts.Stage, err)
+		}
+		routing = aliasRouting{Version: alias.FunctionVersion, Weight: float64(shift.Percent) / 100}
+	}
 
-	// Wait for the S3 upload to propagate
-	fmt.Println("Waiting for S3 upload to propagate...")
-	if err := waitForS3Object(stageConfig.S3Bucket, zipFileName); err != nil {
+	tags, err := stackTags(stageConfig)
+	if err != nil {
 		return err
 	}
 
-	// 6. Generate CloudFormation template
-	if err := generateTemplate("template.yaml", stageConfig); err != nil {
+	// 5. Skip everything if neither the code, the template nor the tags
+	// changed (the Lambda function shares its name with the stack)
+	if isNoopDeploy(stackName, stackName, templateFile, artifact, tags) {
+		fmt.Println("✅ Nothing to deploy: code and template match the deployed stack")
+		return nil
+	}
+
+	// 6. Upload to S3 or push the image to ECR (skipped if already there)
+	if err := publishArtifact(artifact, stageConfig, tempDir); err != nil {
 		return err
 	}
+	if authorizer != nil {
+		if err := uploadArtifact(authorizer, stageConfig.S3Bucket); err != nil {
+			return err
+		}
+	}
 
-	// 7. Update CloudFormation stack
-	if err := updateStack(stackName); err != nil {
+	// 7. Update CloudFormation stack, unless the build outlived the lock
+	if err := lock.Check(); err != nil {
+		return err
+	}
+	if err := updateStack(stackName, templateFile, stageConfig.S3Bucket, tags, routing); err != nil {
 		return err
 	}
 
@@ -112,11 +191,18 @@ func runUpdate(opts *UpdateOptions) error {
 		return err
 	}
 
-	// 10. NOW it's safe to delete the zip file from S3
-	if err := deleteFromS3(stageConfig.S3Bucket, zipFileName); err != nil {
-		fmt.Printf("Warning: failed to clean up S3 file: %v\n", err)
+	// 10. Complete or hand over the traffic shift
+	if shift != nil && shift.Interval > 0 {
+		if err := runLinearShift(stackName, opts.Stage, shift, lock); err != nil {
+			return err
+		}
+	} else if shift != nil {
+		fmt.Printf("🐤 %d%% of traffic is routed to the new version\n", shift.Percent)
+		fmt.Printf("  Run 'gozap promote --stage %s' to shift all traffic or 'gozap abort --stage %s' to revert\n", opts.Stage, opts.Stage)
 	}
 
+	recordHistory("update", stageConfig, stackName)
+
 	fmt.Println("✅ Deployment updated successfully!")
 	return nil
 }
@@ -124,8 +210,8 @@ func runUpdate(opts *UpdateOptions) error {
 func waitForStackUpdate(stackName string) error {
 	fmt.Printf("Waiting for stack '%s' update to complete...\n", stackName)
 
-	waitCmd := exec.Command(
-		"aws", "cloudformation", "wait", "stack-update-complete",
+	waitCmd := awsCommand(
+		"cloudformation", "wait", "stack-update-complete",
 		"--stack-name", stackName,
 	)
 
@@ -158,7 +244,7 @@ func readConfig(configFile string) (map[string]DeploymentConfig, error) {
 
 func checkStackExists(stackName string) error {
 	fmt.Printf("Checking if stack '%s' exists...\n", stackName)
-	describeStack := exec.Command("aws", "cloudformation", "describe-stacks", "--stack-name", stackName)
+	describeStack := awsCommand("cloudformation", "describe-stacks", "--stack-name", stackName)
 	if output, err := describeStack.CombinedOutput(); err != nil {
 		return fmt.Errorf("❌ Stack does not exist or cannot be accessed: %w\n%s", err, output)
 	}
@@ -167,7 +253,12 @@ func checkStackExists(stackName string) error {
 
 func buildProject(binDir string) error {
 	fmt.Println("Building project...")
-	build := exec.Command("go", "build", "-o", filepath.Join(binDir, "bootstrap"), "-ldflags", "-s -w", ".")
+	return buildPackage(".", filepath.Join(binDir, "bootstrap"))
+}
+
+// buildPackage builds a main package of the project into a Lambda binary
+func buildPackage(pkg, output string) error {
+	build := exec.Command("go", "build", "-trimpath", "-o", output, "-ldflags", "-s -w -buildid=", pkg)
 	build.Env = append(os.Environ(), "GOOS=linux", "GOARCH=amd64")
 	if output, err := build.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to build project: %w\n%s", err, output)
@@ -175,26 +266,33 @@ func buildProject(binDir string) error {
 	return nil
 }
 
-func zipProject(zipFileName, filePath string) error {
-	fmt.Println("Creating deployment package...")
-	zip := exec.Command("zip", "-j", zipFileName, filePath)
-	if output, err := zip.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to zip project: %w\n%s", err, output)
-	}
-	return nil
-}
-
 func uploadToS3(localFile, bucket, key string) error {
 	fmt.Printf("Uploading to S3 bucket '%s'...\n", bucket)
-	s3Upload := exec.Command("aws", "s3", "cp", localFile, fmt.Sprintf("s3://%s/%s", bucket, key))
+	s3Upload := awsCommand("s3", "cp", localFile, fmt.Sprintf("s3://%s/%s", bucket, key))
 	if output, err := s3Upload.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to upload to S3: %w\n%s", err, output)
 	}
 	return nil
 }
 
-func generateTemplate(outFile string, config DeploymentConfig) error {
+// generateTemplate renders the stage's template. VPC resources given by tags
+// are looked up and stored in config, so the caller sees the IDs the template
+// was rendered with.
+func generateTemplate(outFile string, config *DeploymentConfig) error {
 	fmt.Println("Generating CloudFormation template...")
+	if err := validateStageConfig(*config); err != nil {
+		return err
+	}
+
+	// Look up VPC resources given by tags
+	if config.Vpc != nil {
+		vpc, err := config.Vpc.resolve()
+		if err != nil {
+			return err
+		}
+		config.Vpc = vpc
+	}
+
 	templateContent, err := templateFS.ReadFile("templates/template.yaml.tmpl")
 	if err != nil {
 		return fmt.Errorf("failed to read template file: %w", err)
@@ -217,13 +315,52 @@ func generateTemplate(outFile string, config DeploymentConfig) error {
 	return nil
 }
 
-func updateStack(stackName string) error {
+// templatePrefix is where rendered templates are uploaded in the deployment
+// bucket. CloudFormation accepts 51,200 bytes inline but 1 MB from S3.
+const templatePrefix = "gozap-templates"
+
+// uploadTemplate uploads a rendered template to the stage's bucket under a
+// content-addressed key and returns its URL. Templates are small and always
+// uploaded, so an older copy about to expire is never the one read.
+func uploadTemplate(templateFile, bucket string) (string, error) {
+	hash, err := hashFile(templateFile)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s/%s.yaml", templatePrefix, hash)
+	if err := uploadToS3(templateFile, bucket, key); err != nil {
+		return "", err
+	}
+
+	host := bucket + ".s3.amazonaws.com"
+	if region := currentTarget.Region; region != "" {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, region)
+	}
+	return fmt.Sprintf("https://%s/%s", host, key), nil
+}
+
+func updateStack(stackName, templateFile, bucket string, tags map[string]string, routing aliasRouting) error {
+	templateURL, err := uploadTemplate(templateFile, bucket)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Updating CloudFormation stack '%s'...\n", stackName)
-	cloudformation := exec.Command(
-		"aws", "cloudformation", "update-stack",
+	tagArg, err := updateTagArg(tags)
+	if err != nil {
+		return err
+	}
+	parameters, err := routing.parameters()
+	if err != nil {
+		return err
+	}
+	cloudformation := awsCommand(
+		"cloudformation", "update-stack",
 		"--stack-name", stackName,
-		"--template-body", "file://template.yaml",
+		"--template-url", templateURL,
 		"--capabilities", "CAPABILITY_NAMED_IAM",
+		"--parameters", parameters,
+		"--tags", tagArg,
 	)
 	if output, err := cloudformation.CombinedOutput(); err != nil {
 		// Check if it's the "No updates are to be performed" error
@@ -241,42 +378,85 @@ func updateStack(stackName string) error {
 
 func outputStackDetails(stackName string) error {
 	fmt.Printf("Fetching details for stack '%s'...\n", stackName)
-	describeStack := exec.Command("aws", "cloudformation", "describe-stacks", "--stack-name", stackName)
+	stack, err := describeStack(stackName)
+	if err != nil {
+		return err
+	}
+
+	if len(stack.Outputs) == 0 {
+		return fmt.Errorf("no stack outputs found")
+	}
+
+	fmt.Println("\nStack Outputs:")
+	for _, output := range stack.Outputs {
+		fmt.Printf("  %s: %s\n", output.OutputKey, output.OutputValue)
+	}
+
+	return nil
+}
+
+type stackDescription struct {
+	StackStatus     string `json:"StackStatus"`
+	LastUpdatedTime string `json:"LastUpdatedTime"`
+	Outputs         []struct {
+		OutputKey   string `json:"OutputKey"`
+		OutputValue string `json:"OutputValue"`
+	} `json:"Outputs"`
+	Tags []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	} `json:"Tags"`
+	Parameters []struct {
+		ParameterKey   string `json:"ParameterKey"`
+		ParameterValue string `json:"ParameterValue"`
+	} `json:"Parameters"`
+}
+
+func describeStack(stackName string) (*stackDescription, error) {
+	describeStack := awsCommand("cloudformation", "describe-stacks", "--stack-name", stackName)
 	output, err := describeStack.Output()
 	if err != nil {
-		return fmt.Errorf("failed to describe stack: %w", err)
+		return nil, fmt.Errorf("failed to describe stack: %w", err)
 	}
 
 	var response struct {
-		Stacks []struct {
-			Outputs []struct {
-				OutputKey   string `json:"OutputKey"`
-				OutputValue string `json:"OutputValue"`
-			} `json:"Outputs"`
-		} `json:"Stacks"`
+		Stacks []stackDescription `json:"Stacks"`
 	}
 
 	if err := json.Unmarshal(output, &response); err != nil {
-		return fmt.Errorf("failed to parse stack details: %w", err)
+		return nil, fmt.Errorf("failed to parse stack details: %w", err)
 	}
 
-	if len(response.Stacks) == 0 || len(response.Stacks[0].Outputs) == 0 {
-		return fmt.Errorf("no stack outputs found")
+	if len(response.Stacks) == 0 {
+		return nil, fmt.Errorf("stack '%s' not found", stackName)
 	}
 
-	fmt.Println("\nStack Outputs:")
-	for _, output := range response.Stacks[0].Outputs {
-		fmt.Printf("  %s: %s\n", output.OutputKey, output.OutputValue)
+	return &response.Stacks[0], nil
+}
+
+// stackTags returns the stack tags keyed by tag name
+func (s *stackDescription) stackTags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range s.Tags {
+		tags[tag.Key] = tag.Value
 	}
+	return tags
+}
 
-	return nil
+// stackOutputs returns the stack outputs keyed by output name
+func (s *stackDescription) stackOutputs() map[string]string {
+	outputs := map[string]string{}
+	for _, output := range s.Outputs {
+		outputs[output.OutputKey] = output.OutputValue
+	}
+	return outputs
 }
 
 func waitForS3Object(bucket, key string) error {
 	fmt.Printf("Verifying S3 object '%s' exists...\n", key)
 	maxAttempts := 5
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		cmd := exec.Command("aws", "s3api", "head-object", "--bucket", bucket, "--key", key)
+		cmd := awsCommand("s3api", "head-object", "--bucket", bucket, "--key", key)
 		if _, err := cmd.CombinedOutput(); err == nil {
 			fmt.Println("S3 object verified successfully!")
 			return nil
@@ -288,15 +468,6 @@ func waitForS3Object(bucket, key string) error {
 	return fmt.Errorf("S3 object not available after %d attempts", maxAttempts)
 }
 
-func deleteFromS3(bucket, key string) error {
-	fmt.Printf("Cleaning up S3 file '%s'...\n", key)
-	s3Delete := exec.Command("aws", "s3", "rm", fmt.Sprintf("s3://%s/%s", bucket, key))
-	if output, err := s3Delete.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to delete from S3: %w\n%s", err, output)
-	}
-	return nil
-}
-
 func cleanupFiles(files []string) {
 	fmt.Println("Cleaning up local files...")
 	for _, file := range files {
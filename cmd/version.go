@@ -0,0 +1,10 @@
+package cmd
+
+// version is the gozap version, set by main through SetVersion
+var version = "dev"
+
+// SetVersion records the version of the gozap binary for the commands that
+// stamp it on deployed resources
+func SetVersion(v string) {
+	version = v
+}
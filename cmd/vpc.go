@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VpcConfig places the function in private subnets. Subnets and security
+// groups are given by ID or looked up by tags at deploy time.
+type VpcConfig struct {
+	SubnetIds         []string          `json:",omitempty"`
+	SecurityGroupIds  []string          `json:",omitempty"`
+	SubnetTags        map[string]string `json:",omitempty"`
+	SecurityGroupTags map[string]string `json:",omitempty"`
+}
+
+func (v *VpcConfig) validate() error {
+	if len(v.SubnetIds) == 0 && len(v.SubnetTags) == 0 {
+		return fmt.Errorf("❌ Vpc requires SubnetIds or SubnetTags")
+	}
+	if len(v.SecurityGroupIds) == 0 && len(v.SecurityGroupTags) == 0 {
+		return fmt.Errorf("❌ Vpc requires SecurityGroupIds or SecurityGroupTags")
+	}
+	return nil
+}
+
+// resolve looks up the subnets and security groups given by tags
+func (v *VpcConfig) resolve() (*VpcConfig, error) {
+	resolved := *v
+
+	if len(v.SubnetIds) == 0 {
+		ids, err := lookupByTags("describe-subnets", "Subnets[].SubnetId", v.SubnetTags)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("❌ no subnets match the tags %v", v.SubnetTags)
+		}
+		resolved.SubnetIds = ids
+	}
+
+	if len(v.SecurityGroupIds) == 0 {
+		ids, err := lookupByTags("describe-security-groups", "SecurityGroups[].GroupId", v.SecurityGroupTags)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("❌ no security groups match the tags %v", v.SecurityGroupTags)
+		}
+		resolved.SecurityGroupIds = ids
+	}
+
+	return &resolved, nil
+}
+
+// lookupByTags returns the sorted IDs of the EC2 resources matching all tags
+func lookupByTags(operation, query string, tags map[string]string) ([]string, error) {
+	args := []string{"ec2", operation, "--query", query, "--output", "json", "--filters"}
+	for key, value := range tags {
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", key, value))
+	}
+
+	output, err := awsCommand(args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up VPC resources: %w\n%s", err, output)
+	}
+
+	var ids []string
+	if err := json.Unmarshal(output, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse VPC resources: %w", err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// checkVpc warns about subnets without a NAT route, since a function in them
+// cannot reach the internet or public AWS endpoints
+func checkVpc(opts *DoctorOptions) []doctorFinding {
+	vpc := opts.StageConfig.Vpc
+	if vpc == nil {
+		return []doctorFinding{{Message: "function is not attached to a VPC"}}
+	}
+
+	resolved, err := vpc.resolve()
+	if err != nil {
+		return []doctorFinding{{Warning: true, Message: err.Error()}}
+	}
+
+	var findings []doctorFinding
+	for _, subnet := range resolved.SubnetIds {
+		hasNat, err := subnetHasNatRoute(subnet)
+		switch {
+		case err != nil:
+			findings = append(findings, doctorFinding{Warning: true, Message: fmt.Sprintf("%s: %v", subnet, err)})
+		case !hasNat:
+			findings = append(findings, doctorFinding{Warning: true, Message: fmt.Sprintf("%s has no NAT route, the function will lose internet access in this subnet", subnet)})
+		default:
+			findings = append(findings, doctorFinding{Message: fmt.Sprintf("%s routes to the internet through NAT", subnet)})
+		}
+	}
+	return findings
+}
+
+func subnetHasNatRoute(subnet string) (bool, error) {
+	table, err := describeRouteTables("Name=association.subnet-id,Values=" + subnet)
+	if err != nil {
+		return false, err
+	}
+
+	// Subnets without an explicit association use the main route table
+	if len(table) == 0 {
+		vpcID, err := awsCommand("ec2", "describe-subnets", "--subnet-ids", subnet,
+			"--query", "Subnets[0].VpcId", "--output", "text").Output()
+		if err != nil {
+			return false, fmt.Errorf("failed to describe subnet: %w", err)
+		}
+		table, err = describeRouteTables("Name=vpc-id,Values="+strings.TrimSpace(string(vpcID)), "Name=association.main,Values=true")
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, rt := range table {
+		for _, route := range rt.Routes {
+			if route.DestinationCidrBlock != "0.0.0.0/0" {
+				continue
+			}
+			if route.NatGatewayId != "" || route.InstanceId != "" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+type routeTable struct {
+	Routes []struct {
+		DestinationCidrBlock string `json:"DestinationCidrBlock"`
+		NatGatewayId         string `json:"NatGatewayId"`
+		InstanceId           string `json:"InstanceId"`
+	} `json:"Routes"`
+}
+
+func describeRouteTables(filters ...string) ([]routeTable, error) {
+	args := append([]string{"ec2", "describe-route-tables", "--query", "RouteTables", "--output", "json", "--filters"}, filters...)
+	output, err := awsCommand(args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables: %w\n%s", err, output)
+	}
+
+	var tables []routeTable
+	if err := json.Unmarshal(output, &tables); err != nil {
+		return nil, fmt.Errorf("failed to parse route tables: %w", err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateStageVpc(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "ids", change: func(c *DeploymentConfig) {
+			c.Vpc = &VpcConfig{SubnetIds: []string{"subnet-1"}, SecurityGroupIds: []string{"sg-1"}}
+		}},
+		{name: "tags", change: func(c *DeploymentConfig) {
+			c.Vpc = &VpcConfig{SubnetTags: map[string]string{"tier": "private"}, SecurityGroupTags: map[string]string{"app": "api"}}
+		}},
+		{name: "without subnets", change: func(c *DeploymentConfig) {
+			c.Vpc = &VpcConfig{SecurityGroupIds: []string{"sg-1"}}
+		}, wantErr: "SubnetIds"},
+		{name: "without security groups", change: func(c *DeploymentConfig) {
+			c.Vpc = &VpcConfig{SubnetIds: []string{"subnet-1"}}
+		}, wantErr: "SecurityGroupIds"},
+	})
+}
+
+func TestVpcResolve(t *testing.T) {
+	fakeAWS(t, `case "$2" in
+  describe-subnets) echo '["subnet-b", "subnet-a"]' ;;
+  describe-security-groups) echo '[]' ;;
+  *) exit 1 ;;
+esac
+`)
+
+	vpc := &VpcConfig{SubnetTags: map[string]string{"tier": "private"}, SecurityGroupIds: []string{"sg-1"}}
+	resolved, err := vpc.resolve()
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	if !reflect.DeepEqual(resolved.SubnetIds, []string{"subnet-a", "subnet-b"}) || !reflect.DeepEqual(resolved.SecurityGroupIds, []string{"sg-1"}) {
+		t.Errorf("resolve() = %+v, want the sorted subnets and the given security group", resolved)
+	}
+	if len(vpc.SubnetIds) != 0 {
+		t.Error("resolve() changed the configured VPC")
+	}
+
+	if _, err := (&VpcConfig{SubnetIds: []string{"subnet-1"}, SecurityGroupTags: map[string]string{"app": "none"}}).resolve(); err == nil {
+		t.Error("resolve() succeeded although no security group matches the tags")
+	}
+}
+
+func TestVpcTemplate(t *testing.T) {
+	stageConfig := deployableStage()
+	stageConfig.Vpc = &VpcConfig{SubnetIds: []string{"subnet-1", "subnet-2"}, SecurityGroupIds: []string{"sg-1"}}
+	template := renderTemplate(t, stageConfig)
+
+	var lambda struct {
+		VpcConfig struct {
+			SecurityGroupIds []string `yaml:"SecurityGroupIds"`
+			SubnetIds        []string `yaml:"SubnetIds"`
+		} `yaml:"VpcConfig"`
+	}
+	template.resource(t, "Lambda", "AWS::Lambda::Function", &lambda)
+	if !reflect.DeepEqual(lambda.VpcConfig.SubnetIds, stageConfig.Vpc.SubnetIds) || !reflect.DeepEqual(lambda.VpcConfig.SecurityGroupIds, stageConfig.Vpc.SecurityGroupIds) {
+		t.Errorf("Lambda VpcConfig = %+v", lambda.VpcConfig)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// managedRuleGroupName matches the AWS managed rule groups (vendor AWS), which
+// all share the AWSManagedRules prefix
+var managedRuleGroupName = regexp.MustCompile(`^AWSManagedRules[A-Za-z0-9]+$`)
+
+// defaultWafRuleGroups are the AWS managed rule groups of generated web ACLs
+var defaultWafRuleGroups = []string{
+	"AWSManagedRulesCommonRuleSet",
+	"AWSManagedRulesKnownBadInputsRuleSet",
+	"AWSManagedRulesAmazonIpReputationList",
+}
+
+// WafConfig protects the API stage with a WAF web ACL: either an existing
+// one given by WebAclArn or one generated from AWS managed rule groups with
+// an optional per-IP rate limit.
+type WafConfig struct {
+	WebAclArn         string   `json:",omitempty"`
+	ManagedRuleGroups []string `json:",omitempty"`
+	RateLimit         int      `json:",omitempty"` // requests per IP in 5 minutes
+}
+
+func (w *WafConfig) validate() error {
+	if w.WebAclArn != "" {
+		if len(w.ManagedRuleGroups) > 0 || w.RateLimit != 0 {
+			return fmt.Errorf("❌ Waf takes either WebAclArn or ManagedRuleGroups/RateLimit, not both")
+		}
+		if !strings.HasPrefix(w.WebAclArn, "arn:") || !strings.Contains(w.WebAclArn, ":wafv2:") || !strings.Contains(w.WebAclArn, ":regional/webacl/") {
+			return fmt.Errorf("❌ '%s' is not the ARN of a regional WAFv2 web ACL", w.WebAclArn)
+		}
+		return nil
+	}
+
+	for _, group := range w.ManagedRuleGroups {
+		if !managedRuleGroupName.MatchString(group) {
+			return fmt.Errorf("❌ '%s' is not the name of an AWS managed rule group", group)
+		}
+	}
+	if w.RateLimit != 0 && (w.RateLimit < 10 || w.RateLimit > 2000000000) {
+		return fmt.Errorf("❌ Waf RateLimit must be between 10 and 2000000000 requests per 5 minutes, got %d", w.RateLimit)
+	}
+	return nil
+}
+
+// RuleGroups returns the managed rule groups of the generated web ACL
+func (w *WafConfig) RuleGroups() []string {
+	if len(w.ManagedRuleGroups) == 0 {
+		return defaultWafRuleGroups
+	}
+	return w.ManagedRuleGroups
+}
+
+// RatePriority places the rate-based rule after the managed rule groups
+func (w *WafConfig) RatePriority() int {
+	return len(w.RuleGroups())
+}
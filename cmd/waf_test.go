@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWafConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		waf     WafConfig
+		wantErr bool
+	}{
+		{name: "defaults", waf: WafConfig{}},
+		{name: "web acl", waf: WafConfig{WebAclArn: "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/api/1"}},
+		{name: "web acl and rules", waf: WafConfig{WebAclArn: "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/api/1", RateLimit: 100}, wantErr: true},
+		{name: "global web acl", waf: WafConfig{WebAclArn: "arn:aws:wafv2:us-east-1:123456789012:global/webacl/api/1"}, wantErr: true},
+		{name: "managed rule group", waf: WafConfig{ManagedRuleGroups: []string{"AWSManagedRulesLinuxRuleSet"}}},
+		{name: "not a managed rule group", waf: WafConfig{ManagedRuleGroups: []string{"my_rules"}}, wantErr: true},
+		{name: "rate limit too low", waf: WafConfig{RateLimit: 9}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.waf.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStageWaf(t *testing.T) {
+	runStageCases(t, []stageCase{
+		{name: "waf", change: func(c *DeploymentConfig) {
+			c.Waf = &WafConfig{ManagedRuleGroups: []string{"AWSManagedRulesSQLiRuleSet"}, RateLimit: 1000}
+		}},
+		{name: "waf rule group", change: func(c *DeploymentConfig) {
+			c.Waf = &WafConfig{ManagedRuleGroups: []string{"SQLi"}}
+		}, wantErr: "managed rule group"},
+	})
+}
+
+func TestWafTemplate(t *testing.T) {
+	type rule struct {
+		Name     string `yaml:"Name"`
+		Priority int    `yaml:"Priority"`
+	}
+	type association struct {
+		WebACLArn any `yaml:"WebACLArn"`
+	}
+
+	t.Run("generated web acl", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Waf = &WafConfig{RateLimit: 1000}
+		template := renderTemplate(t, stageConfig)
+
+		var webAcl struct {
+			Rules []rule `yaml:"Rules"`
+			Scope string `yaml:"Scope"`
+		}
+		template.resource(t, "WebAcl", "AWS::WAFv2::WebACL", &webAcl)
+		var want []rule
+		for i, group := range defaultWafRuleGroups {
+			want = append(want, rule{Name: group, Priority: i})
+		}
+		want = append(want, rule{Name: "RateLimit", Priority: len(defaultWafRuleGroups)})
+		if webAcl.Scope != "REGIONAL" || !reflect.DeepEqual(webAcl.Rules, want) {
+			t.Errorf("WebAcl = %+v, want the default rule groups and the rate limit", webAcl)
+		}
+		var generated association
+		template.resource(t, "WebAclAssociation", "AWS::WAFv2::WebACLAssociation", &generated)
+		if generated.WebACLArn != "WebAcl.Arn" {
+			t.Errorf("WebAclAssociation WebACLArn = %v, want the generated web ACL", generated.WebACLArn)
+		}
+	})
+
+	t.Run("existing web acl", func(t *testing.T) {
+		stageConfig := deployableStage()
+		stageConfig.Waf = &WafConfig{WebAclArn: "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/api/1"}
+		template := renderTemplate(t, stageConfig)
+
+		if _, ok := template.Resources["WebAcl"]; ok {
+			t.Error("a web ACL is generated although the stage uses an existing one")
+		}
+		var existing association
+		template.resource(t, "WebAclAssociation", "AWS::WAFv2::WebACLAssociation", &existing)
+		if existing.WebACLArn != stageConfig.Waf.WebAclArn {
+			t.Errorf("WebAclAssociation WebACLArn = %v, want %s", existing.WebACLArn, stageConfig.Waf.WebAclArn)
+		}
+	})
+}
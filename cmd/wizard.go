@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// prompter reads answers for the interactive init wizard from stdin
+type prompter struct {
+	reader *bufio.Reader
+}
+
+func newPrompter() *prompter {
+	return &prompter{reader: bufio.NewReader(os.Stdin)}
+}
+
+// isInteractive reports whether stdin is attached to a terminal
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ask prompts for a value, returning def when the answer is empty
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	answer, _ := p.reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// askRequired prompts until a non-empty value is given
+func (p *prompter) askRequired(question, def string) string {
+	for {
+		if answer := p.ask(question, def); answer != "" {
+			return answer
+		}
+		fmt.Println("  A value is required")
+	}
+}
+
+// askInt prompts until a whole number within [min, max] is given
+func (p *prompter) askInt(question string, def, min, max int) int {
+	for {
+		answer := p.ask(question, strconv.Itoa(def))
+		value, err := strconv.Atoi(answer)
+		if err == nil && value >= min && value <= max {
+			return value
+		}
+		fmt.Printf("  Enter a number between %d and %d\n", min, max)
+	}
+}
+
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Printf("%s (%s): ", question, hint)
+
+	answer, _ := p.reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// runInitWizard prompts for every init option that was not given as a flag
+func runInitWizard(cmd *cobra.Command, opts *InitOptions) error {
+	p := newPrompter()
+	fmt.Println("🧙 GoZap project setup (use --non-interactive to skip the prompts)")
+	fmt.Println()
+
+	if opts.ProjectName == "" {
+		cwd, _ := os.Getwd()
+		opts.ProjectName = p.askRequired("Project name", filepath.Base(cwd))
+	}
+
+	if opts.Stage == "" {
+		existing, _ := readConfig("config.json")
+		for {
+			opts.Stage = p.askRequired("Stage", "dev")
+			if _, exists := existing[opts.Stage]; !exists {
+				break
+			}
+			fmt.Printf("  Stage '%s' already exists in config.json, choose another name\n", opts.Stage)
+		}
+	}
+
+	if opts.S3Bucket == "" {
+		bucket, err := promptBucket(p)
+		if err != nil {
+			return err
+		}
+		opts.S3Bucket = bucket
+	}
+
+	if !cmd.Flags().Changed("memory") {
+		fmt.Println("💡 128 MB suits most APIs, CPU share grows with memory so raise it for CPU bound work")
+		opts.Memory = p.askInt("Memory (MB)", opts.Memory, 128, 10240)
+	}
+
+	if !cmd.Flags().Changed("timeout") {
+		fmt.Println("💡 API Gateway stops waiting after 29 seconds, longer timeouts only help non-HTTP invocations")
+		opts.Timeout = p.askInt("Timeout (seconds)", opts.Timeout, 1, 900)
+	}
+
+	if !cmd.Flags().Changed("scaffold") {
+		opts.Scaffold = p.confirm("Generate a starter application?", false)
+		if opts.Scaffold && !cmd.Flags().Changed("framework") {
+			for {
+				opts.Framework = p.ask("Framework (gin, echo, chi, fiber, nethttp)", opts.Framework)
+				if _, ok := frameworkAdapters[opts.Framework]; ok {
+					break
+				}
+				fmt.Printf("  Unsupported framework '%s'\n", opts.Framework)
+			}
+		}
+	}
+
+	opts.Interactive = true
+	fmt.Println()
+	return nil
+}
+
+// promptBucket lets the user pick an accessible bucket or create a new one
+func promptBucket(p *prompter) (string, error) {
+	buckets, err := listBuckets()
+	if err != nil {
+		fmt.Printf("Warning: could not list S3 buckets: %v\n", err)
+	}
+
+	if len(buckets) > 0 {
+		fmt.Println("Accessible S3 buckets:")
+		for i, bucket := range buckets {
+			fmt.Printf("  %d) %s\n", i+1, bucket)
+		}
+	}
+
+	bootstrapped := bootstrapBucket(defaultRegion())
+	if bootstrapped != "" {
+		fmt.Printf("💡 Press enter to use the bootstrapped bucket '%s'\n", bootstrapped)
+	}
+
+	for {
+		answer := p.askRequired("Deployment bucket (number or name)", bootstrapped)
+		if i, err := strconv.Atoi(answer); err == nil {
+			if i < 1 || i > len(buckets) {
+				fmt.Printf("  Choose a number between 1 and %d\n", len(buckets))
+				continue
+			}
+			return buckets[i-1], nil
+		}
+
+		if slices.Contains(buckets, answer) {
+			return answer, nil
+		}
+
+		if !p.confirm(fmt.Sprintf("Bucket '%s' was not found. Create it with encryption and versioning?", answer), true) {
+			continue
+		}
+		if err := createDeploymentBucket(answer); err != nil {
+			return "", err
+		}
+		return answer, nil
+	}
+}
+
+func listBuckets() ([]string, error) {
+	cmd := awsCommand("s3api", "list-buckets", "--query", "Buckets[].Name", "--output", "json")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []string
+	if err := json.Unmarshal(output, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to parse bucket list: %w", err)
+	}
+	return buckets, nil
+}
+
+// createDeploymentBucket creates a private bucket with default encryption
+// and versioning in the configured region
+func createDeploymentBucket(bucket string) error {
+	fmt.Printf("Creating S3 bucket '%s'...\n", bucket)
+
+	args := []string{"s3api", "create-bucket", "--bucket", bucket}
+	if region := defaultRegion(); region != "" && region != "us-east-1" {
+		args = append(args, "--create-bucket-configuration", "LocationConstraint="+region)
+	}
+
+	steps := [][]string{
+		args,
+		{"s3api", "put-public-access-block", "--bucket", bucket, "--public-access-block-configuration",
+			"BlockPublicAcls=true,IgnorePublicAcls=true,BlockPublicPolicy=true,RestrictPublicBuckets=true"},
+		{"s3api", "put-bucket-encryption", "--bucket", bucket, "--server-side-encryption-configuration",
+			`{"Rules":[{"ApplyServerSideEncryptionByDefault":{"SSEAlgorithm":"AES256"}}]}`},
+		{"s3api", "put-bucket-versioning", "--bucket", bucket, "--versioning-configuration", "Status=Enabled"},
+	}
+
+	for _, step := range steps {
+		if output, err := awsCommand(step...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set up bucket '%s' (%s): %w\n%s", bucket, step[1], err, output)
+		}
+	}
+
+	fmt.Println("Bucket created successfully!")
+	return nil
+}
+
+// defaultRegion returns the region the aws CLI would use for the current
+// target: the override, the environment, then the profile's configuration
+func defaultRegion() string {
+	if targetOverrides.Region != "" {
+		return targetOverrides.Region
+	}
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+	output, err := awsCommand("configure", "get", "region").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// previewStage prints the stage entry as it will be written to config.json
+func previewStage(stage string, stageConfig DeploymentConfig) {
+	preview, _ := json.MarshalIndent(map[string]DeploymentConfig{stage: stageConfig}, "", "  ")
+	fmt.Println("📄 The following stage will be added to config.json:")
+	fmt.Println(string(preview))
+}
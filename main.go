@@ -21,10 +21,22 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
+	cmd.SetVersion(Version)
+	cmd.AddTargetFlags(rootCmd)
+
 	rootCmd.AddCommand(cmd.NewInitCommand())
 	rootCmd.AddCommand(cmd.NewDeployCommand())
 	rootCmd.AddCommand(cmd.NewUpdateCommand())
 	rootCmd.AddCommand(cmd.NewUndeployCommand())
+	rootCmd.AddCommand(cmd.NewPromoteCommand())
+	rootCmd.AddCommand(cmd.NewAbortCommand())
+	rootCmd.AddCommand(cmd.NewStatusCommand())
+	rootCmd.AddCommand(cmd.NewHistoryCommand())
+	rootCmd.AddCommand(cmd.NewLockCommand())
+	rootCmd.AddCommand(cmd.NewApiKeyCommand())
+	rootCmd.AddCommand(cmd.NewDoctorCommand())
+	rootCmd.AddCommand(cmd.NewBootstrapCommand())
+	rootCmd.AddCommand(cmd.NewStageCommand())
 
 	rootCmd.SetVersionTemplate("GoZap Version: {{.Version}} \n")
 }